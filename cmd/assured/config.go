@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix prefixes the environment variables that configure assured, such as ASSURED_TLS_CERT for -tlsCert
+const envPrefix = "ASSURED_"
+
+// repeatable is a flag that collects every value it is set to. Its environment variable separates values with ';'.
+type repeatable interface {
+	repeatable()
+}
+
+func (p *paths) repeatable()    {}
+func (s *services) repeatable() {}
+
+// fileFlags are the flags naming files, whose relative paths in a config file are relative to the config file
+var fileFlags = map[string]bool{
+	"preload":         true,
+	"openapi":         true,
+	"tlsCert":         true,
+	"tlsKey":          true,
+	"tlsClientCA":     true,
+	"contract":        true,
+	"grpcDescriptors": true,
+}
+
+// envName returns the environment variable of a flag, its name in upper snake case with the ASSURED_ prefix
+func envName(name string) string {
+	var b strings.Builder
+	b.WriteString(envPrefix)
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(runes[i-1]) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// loadConfigFile parses a YAML or JSON config file of flag names to values. Repeatable flags may be given a list.
+// Relative file paths are resolved relative to the config file.
+func loadConfigFile(path string) (map[string][]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	config := map[string][]string{}
+	for name, value := range raw {
+		switch v := value.(type) {
+		case nil:
+		case []any:
+			for _, item := range v {
+				config[name] = append(config[name], fmt.Sprint(item))
+			}
+		case map[string]any:
+			return nil, fmt.Errorf("config %s must be a value or a list of values", name)
+		default:
+			config[name] = []string{fmt.Sprint(v)}
+		}
+		if fileFlags[name] {
+			for i, value := range config[name] {
+				if value != "" && !filepath.IsAbs(value) {
+					config[name][i] = filepath.Join(filepath.Dir(path), value)
+				}
+			}
+		}
+	}
+	return config, nil
+}
+
+// configure sets the flags not given on the command line from their ASSURED_* environment variables, and then from
+// the config file named by the config flag or ASSURED_CONFIG. Flags take precedence over the environment, which
+// takes precedence over the config file.
+func configure(fs *flag.FlagSet, configFlag string) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	config := map[string][]string{}
+	path := fs.Lookup(configFlag).Value.String()
+	if env, ok := os.LookupEnv(envName(configFlag)); ok && !set[configFlag] {
+		path = env
+	}
+	if path != "" {
+		var err error
+		if config, err = loadConfigFile(path); err != nil {
+			return err
+		}
+		for name := range config {
+			if f := fs.Lookup(name); f == nil || name == configFlag {
+				return fmt.Errorf("unknown config %s in config file %s", name, path)
+			}
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || set[f.Name] || f.Name == configFlag {
+			return
+		}
+		_, multi := f.Value.(repeatable)
+		values, ok := config[f.Name]
+		if env, found := os.LookupEnv(envName(f.Name)); found {
+			values, ok = []string{env}, true
+			if multi {
+				values = nil
+				for value := range strings.SplitSeq(env, ";") {
+					if value = strings.TrimSpace(value); value != "" {
+						values = append(values, value)
+					}
+				}
+			}
+		}
+		if !ok {
+			return
+		}
+		if !multi && len(values) > 1 {
+			err = fmt.Errorf("config %s does not take a list of values", f.Name)
+			return
+		}
+		for _, value := range values {
+			if setErr := fs.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %w", value, f.Name, setErr)
+				return
+			}
+		}
+	})
+	return err
+}
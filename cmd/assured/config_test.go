@@ -0,0 +1,184 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "port", want: "ASSURED_PORT"},
+		{name: "tlsCert", want: "ASSURED_TLS_CERT"},
+		{name: "tlsClientCA", want: "ASSURED_TLS_CLIENT_CA"},
+		{name: "grpcDescriptors", want: "ASSURED_GRPC_DESCRIPTORS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, envName(tt.name))
+		})
+	}
+}
+
+// testFlagSet returns a fresh flag set with a flag of each kind configure handles
+func testFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("assured", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.String("config", "", "")
+	fs.Int("port", 0, "")
+	fs.String("host", "localhost", "")
+	fs.Bool("track", true, "")
+	fs.String("tlsCert", "", "")
+	fs.String("tlsKey", "", "")
+	fs.String("contract", "", "")
+	var preloads paths
+	fs.Var(&preloads, "preload", "")
+	var virtualServices services
+	fs.Var(&virtualServices, "service", "")
+	return fs
+}
+
+func TestConfigure(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		file    string
+		ext     string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			want: map[string]string{"port": "0", "host": "localhost", "track": "true", "preload": ""},
+		},
+		{
+			name: "yaml file",
+			file: "port: 8080\nhost: mocks.local\ntrack: false\npreload:\n  - stubs\n  - /abs/refunds.yaml\n",
+			ext:  ".yaml",
+			want: map[string]string{"port": "8080", "host": "mocks.local", "track": "false", "preload": "{dir}/stubs,/abs/refunds.yaml"},
+		},
+		{
+			name: "json file",
+			file: `{"port": 8080, "preload": "stubs.json", "service": ["payments,prefix=/payments", "refunds"]}`,
+			ext:  ".json",
+			want: map[string]string{"port": "8080", "preload": "{dir}/stubs.json", "service": "payments,refunds"},
+		},
+		{
+			name: "file paths relative to the file",
+			file: "tlsCert: certs/cert.pem\ntlsKey: /etc/assured/key.pem\ncontract: ../contract.yaml\n",
+			ext:  ".yaml",
+			want: map[string]string{"tlsCert": "{dir}/certs/cert.pem", "tlsKey": "/etc/assured/key.pem", "contract": "{parent}/contract.yaml"},
+		},
+		{
+			name: "env over file",
+			env:  map[string]string{"ASSURED_PORT": "9090", "ASSURED_TLS_CERT": "cert.pem"},
+			file: "port: 8080\nhost: mocks.local\ntlsCert: file.pem\n",
+			ext:  ".yaml",
+			want: map[string]string{"port": "9090", "host": "mocks.local", "tlsCert": "cert.pem"},
+		},
+		{
+			name: "flags over env and file",
+			args: []string{"-port", "7070", "-preload", "flag.json"},
+			env:  map[string]string{"ASSURED_PORT": "9090", "ASSURED_PRELOAD": "env.json"},
+			file: "port: 8080\npreload: file.json\n",
+			ext:  ".yaml",
+			want: map[string]string{"port": "7070", "preload": "flag.json"},
+		},
+		{
+			name: "env lists split on semicolons",
+			env:  map[string]string{"ASSURED_PRELOAD": "a.json; b.json;;c.json", "ASSURED_SERVICE": "payments;refunds,port=9000"},
+			want: map[string]string{"preload": "a.json,b.json,c.json", "service": "payments,refunds"},
+		},
+		{
+			name: "env semicolons kept for single flags",
+			env:  map[string]string{"ASSURED_HOST": "a;b"},
+			want: map[string]string{"host": "a;b"},
+		},
+		{
+			name:    "list for single flag",
+			file:    "port:\n  - 8080\n  - 9090\n",
+			ext:     ".yaml",
+			wantErr: "config port does not take a list of values",
+		},
+		{
+			name:    "unknown flag",
+			file:    "ports: 8080\n",
+			ext:     ".yaml",
+			wantErr: "unknown config ports",
+		},
+		{
+			name:    "nested value",
+			file:    "host:\n  name: mocks.local\n",
+			ext:     ".yaml",
+			wantErr: "config host must be a value or a list of values",
+		},
+		{
+			name:    "invalid value",
+			env:     map[string]string{"ASSURED_PORT": "eighty"},
+			wantErr: `invalid value "eighty" for port`,
+		},
+		{
+			name:    "invalid file",
+			file:    "port: [8080",
+			ext:     ".yaml",
+			wantErr: "parse config file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"ASSURED_CONFIG", "ASSURED_PORT", "ASSURED_HOST", "ASSURED_TRACK", "ASSURED_TLS_CERT",
+				"ASSURED_TLS_KEY", "ASSURED_CONTRACT", "ASSURED_PRELOAD", "ASSURED_SERVICE"} {
+				t.Setenv(name, "")
+				require.NoError(t, os.Unsetenv(name))
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			dir := filepath.Join(t.TempDir(), "config")
+			if tt.file != "" {
+				require.NoError(t, os.MkdirAll(dir, 0o755))
+				path := filepath.Join(dir, "assured"+tt.ext)
+				require.NoError(t, os.WriteFile(path, []byte(tt.file), 0o600))
+				t.Setenv("ASSURED_CONFIG", path)
+			}
+
+			fs := testFlagSet()
+			require.NoError(t, fs.Parse(tt.args))
+			err := configure(fs, "config")
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			for name, want := range tt.want {
+				want = strings.ReplaceAll(want, "{dir}", dir)
+				want = strings.ReplaceAll(want, "{parent}", filepath.Dir(dir))
+				require.Equal(t, want, fs.Lookup(name).Value.String(), name)
+			}
+		})
+	}
+}
+
+func TestConfigureConfigFlag(t *testing.T) {
+	dir := t.TempDir()
+	flagPath := filepath.Join(dir, "flag.yaml")
+	envPath := filepath.Join(dir, "env.yaml")
+	require.NoError(t, os.WriteFile(flagPath, []byte("port: 8080\n"), 0o600))
+	require.NoError(t, os.WriteFile(envPath, []byte("port: 9090\n"), 0o600))
+	t.Setenv("ASSURED_CONFIG", envPath)
+
+	fs := testFlagSet()
+	require.NoError(t, fs.Parse([]string{"-config", flagPath}))
+	require.NoError(t, configure(fs, "config"))
+	require.Equal(t, "8080", fs.Lookup("port").Value.String())
+}
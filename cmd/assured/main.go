@@ -2,20 +2,67 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/jesse0michael/go-rest-assured/v5/pkg/assured"
 )
 
-// Preload is the expected format for preloading assured endpoints through the go rest assured application
-type Preload struct {
-	Calls []assured.Call `json:"calls"`
+// paths is a flag that can be repeated to collect multiple paths, in order
+type paths []string
+
+func (p *paths) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *paths) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+// services is a flag that can be repeated to host multiple virtual services, such as name,host=h,prefix=p,port=n
+type services []assured.Service
+
+func (s *services) String() string {
+	names := []string{}
+	for _, service := range *s {
+		names = append(names, service.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (s *services) Set(value string) error {
+	fields := strings.Split(value, ",")
+	service := assured.Service{Name: strings.TrimSpace(fields[0])}
+	if service.Name == "" {
+		return fmt.Errorf("service name is required")
+	}
+	for _, field := range fields[1:] {
+		key, val, _ := strings.Cut(strings.TrimSpace(field), "=")
+		switch key {
+		case "host":
+			service.Host = val
+		case "prefix":
+			service.PathPrefix = val
+		case "port":
+			port, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("invalid service port %q", val)
+			}
+			service.Listen, service.Port = true, port
+		default:
+			return fmt.Errorf("unknown service option %q", key)
+		}
+	}
+	*s = append(*s, service)
+	return nil
 }
 
 func main() {
@@ -26,45 +73,194 @@ func main() {
 		cancel(fmt.Errorf("%s", <-sig))
 	}()
 
+	flag.String("config", "", "a YAML or JSON file of flag names to values. flags and ASSURED_* environment variables take precedence.")
 	port := flag.Int("port", 0, "a port to listen on. default automatically assigns a port.")
-	preload := flag.String("preload", "", "a file to parse preloaded calls from.")
+	var preloads paths
+	flag.Var(&preloads, "preload", "a file, directory or glob pattern of JSON or YAML files to parse preloaded calls from. may be repeated.")
+	watch := flag.Bool("watch", false, "a flag to reload the preloaded calls when the preload files change.")
+	openapi := flag.String("openapi", "", "an OpenAPI 3 document to stub a call for every operation from.")
 	trackMade := flag.Bool("track", true, "a flag to enable the storing of calls made to the service.")
 	host := flag.String("host", "localhost", "a host to use in the client's url.")
 	tlsCert := flag.String("tlsCert", "", "location of tls cert for serving https traffic. tlsKey also required, if specified.")
 	tlsKey := flag.String("tlsKey", "", "location of tls key for serving https traffic. tlsCert also required, if specified")
+	tlsGenerate := flag.Bool("tlsGenerate", false, "a flag to serve https traffic with a certificate issued by a generated ca, served at /assured/ca.")
+	tlsClientCA := flag.String("tlsClientCA", "", "location of the ca to verify client certificates against. defaults to the generated ca, if tlsGenerate specified.")
+	tlsPort := flag.Int("tlsPort", 0, "a port to serve https traffic on, alongside http traffic on port. default serves only https traffic on port.")
+	tlsClientAuth := flag.String("tlsClientAuth", "", "either 'verify' client certificates, if given, or 'require' them.")
+	adminPrefix := flag.String("adminPrefix", assured.DefaultAdminPrefix, "a path prefix of the assured admin endpoints.")
+	adminPort := flag.Int("adminPort", 0, "a port to serve the assured admin endpoints on, apart from the stubbed endpoints on port. default serves both on port.")
+	adminToken := flag.String("adminToken", "", "a bearer token required to call the assured admin endpoints.")
+	adminBasicAuth := flag.String("adminBasicAuth", "", "basic auth credentials, as username:password, required to call the assured admin endpoints.")
+	contract := flag.String("contract", "", "an OpenAPI 3 document to validate calls against.")
+	validateStubs := flag.Bool("validateStubs", false, "a flag to reject stubbed calls that do not conform to the contract.")
+	validateRequests := flag.String("validateRequests", "", "validate requests against the contract and either 'record' or 'reject' non-conforming requests.")
+	grpcDescriptors := flag.String("grpcDescriptors", "", "a protobuf descriptor set of the gRPC services to stub. serves gRPC, if specified.")
+	grpcPort := flag.Int("grpcPort", 0, "a port to listen on for gRPC. default automatically assigns a port.")
+	protocols := flag.String("protocols", "", "a comma separated list of http protocols to serve from http1, h2 and h2c. default serves http1 and, with tls, h2.")
+	sessionTTL := flag.Duration("sessionTTL", assured.DefaultSessionTTL, "a duration a session may go unused before it is ended. 0 keeps sessions until they are ended.")
+	logLevel := flag.String("logLevel", "info", "a level to log at from debug, info, warn and error.")
+	logFormat := flag.String("logFormat", "text", "a format to log in, either 'text' or 'json'.")
+	var virtualServices services
+	flag.Var(&virtualServices, "service", "a virtual service to host as name,host=h,prefix=p,port=n, routed by host header, path prefix or its own port. may be repeated.")
 
 	flag.Parse()
 
-	a := assured.NewAssured(
+	// Set flags not given on the command line from the environment and config file
+	if err := configure(flag.CommandLine, "config"); err != nil {
+		slog.ErrorContext(ctx, "failed to configure assured", "error", err)
+		os.Exit(1)
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		slog.ErrorContext(ctx, "unknown log level", "logLevel", *logLevel)
+		os.Exit(1)
+	}
+	switch *logFormat {
+	case "text":
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+	default:
+		slog.ErrorContext(ctx, "unknown log format", "logFormat", *logFormat)
+		os.Exit(1)
+	}
+
+	opts := []assured.ServerOption{
 		assured.WithPort(*port),
 		assured.WithCallTracking(*trackMade),
 		assured.WithHost(*host),
-		assured.WithTLS(*tlsCert, *tlsKey))
+		assured.WithTLS(*tlsCert, *tlsKey),
+		assured.WithLogger(slog.Default()),
+		assured.WithSessionTTL(*sessionTTL),
+	}
+
+	// If tls generation specified, serve https with a generated ca
+	if *tlsGenerate {
+		opts = append(opts, assured.WithGeneratedTLS())
+	}
+
+	// If tls port specified, serve http and https on separate ports
+	if *tlsPort != 0 {
+		opts = append(opts, assured.WithHTTPAndHTTPS(), assured.WithTLSPort(*tlsPort))
+	}
+
+	// If client auth specified, verify client certificates
+	switch *tlsClientAuth {
+	case "":
+	case "verify", "require":
+		opts = append(opts, assured.WithMutualTLS(*tlsClientCA, *tlsClientAuth == "require"))
+	default:
+		slog.ErrorContext(ctx, "unknown tls client auth", "tlsClientAuth", *tlsClientAuth)
+		os.Exit(1)
+	}
+
+	// If admin port specified, serve the admin endpoints apart from the stubbed endpoints
+	opts = append(opts, assured.WithAdminPrefix(*adminPrefix))
+	if *adminPort != 0 {
+		opts = append(opts, assured.WithAdminListener(), assured.WithAdminPort(*adminPort))
+	}
+
+	// If admin credentials specified, require them on the assured admin endpoints
+	opts = append(opts, assured.WithAdminToken(*adminToken))
+	if *adminBasicAuth != "" {
+		username, password, ok := strings.Cut(*adminBasicAuth, ":")
+		if !ok {
+			slog.ErrorContext(ctx, "admin basic auth must be username:password")
+			os.Exit(1)
+		}
+		opts = append(opts, assured.WithAdminBasicAuth(username, password))
+	}
+
+	// If contract specified, validate calls against the OpenAPI document
+	if *contract != "" {
+		spec, err := assured.LoadOpenAPI(*contract)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to load contract", "error", err)
+			os.Exit(1)
+		}
+		if *validateStubs {
+			opts = append(opts, assured.WithStubValidation(spec))
+		}
+		if *validateRequests != "" {
+			opts = append(opts, assured.WithRequestValidation(spec, assured.ValidationMode(*validateRequests)))
+		}
+	}
+
+	// If gRPC descriptors specified, serve gRPC for the described services
+	if *grpcDescriptors != "" {
+		files, err := assured.LoadDescriptorSet(*grpcDescriptors)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to load grpc descriptors", "error", err)
+			os.Exit(1)
+		}
+		opts = append(opts, assured.WithGRPC(files), assured.WithGRPCPort(*grpcPort))
+	}
+
+	// If protocols specified, serve only the listed http protocols
+	if *protocols != "" {
+		var served []assured.Protocol
+		for _, protocol := range strings.Split(*protocols, ",") {
+			switch p := assured.Protocol(strings.TrimSpace(protocol)); p {
+			case assured.ProtocolHTTP1, assured.ProtocolHTTP2, assured.ProtocolH2C:
+				served = append(served, p)
+			default:
+				slog.ErrorContext(ctx, "unknown protocol", "protocol", protocol)
+				os.Exit(1)
+			}
+		}
+		opts = append(opts, assured.WithProtocols(served...))
+	}
+
+	for _, service := range virtualServices {
+		opts = append(opts, assured.WithService(service))
+	}
+
+	a := assured.NewAssured(opts...)
 
 	go func() {
-		slog.InfoContext(ctx, "starting assured server", "port", a.Port)
+		slog.InfoContext(ctx, "starting assured server", "port", a.Port, "admin_port", a.AdminPort, "tls_port", a.TLSPort, "grpc_port", a.GRPCPort)
 		if err := a.Serve(ctx); err != nil {
 			slog.InfoContext(ctx, "assured server stopped serving", "error", err)
 		}
 	}()
 
-	// If preload file specified, parse the file and load all calls into the assured client
-	if *preload != "" {
-		b, err := os.ReadFile(*preload)
+	// If OpenAPI document specified, stub a call for every operation in the document
+	if *openapi != "" {
+		spec, err := assured.LoadOpenAPI(*openapi)
 		if err != nil {
-			slog.InfoContext(ctx, "failed to read preload file", "error", err)
+			slog.InfoContext(ctx, "failed to load openapi document", "error", err)
+			cancel(err)
+		} else if calls, err := spec.Calls(); err != nil {
+			slog.InfoContext(ctx, "failed to generate openapi calls", "error", err)
+			cancel(err)
+		} else if err = a.Given(ctx, calls...); err != nil {
+			slog.InfoContext(ctx, "failed to set given openapi calls", "error", err)
 			cancel(err)
 		}
-		var preload Preload
-		// TODO response won't unmarshal string to []byte
-		if err := json.Unmarshal(b, &preload); err != nil {
-			slog.InfoContext(ctx, "failed to unmarshal preload file", "error", err)
+	}
+
+	// If preload files specified, parse the files and load all calls into the assured client
+	if len(preloads) > 0 {
+		preload, err := assured.LoadPreload(preloads...)
+		if err != nil {
+			slog.InfoContext(ctx, "failed to load preload files", "error", err)
 			cancel(err)
 		}
-		if err = a.Given(ctx, preload.Calls...); err != nil {
+		stubs, err := a.SwapStubs(ctx, nil, preload.Calls...)
+		if err != nil {
 			slog.InfoContext(ctx, "failed to set given preload file calls", "error", err)
 			cancel(err)
 		}
+		for _, callback := range preload.Callbacks {
+			if err = a.Trigger(ctx, callback); err != nil {
+				slog.InfoContext(ctx, "failed to trigger preload file callback", "error", err)
+				cancel(err)
+			}
+		}
+		if *watch {
+			go assured.WatchPreload(ctx, a.Client, slog.Default(), time.Second, stubs, preloads...)
+		}
 	}
 
 	<-ctx.Done()
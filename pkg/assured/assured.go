@@ -1,6 +1,9 @@
 package assured
 
-import "context"
+import (
+	"context"
+	"net/http"
+)
 
 type Assured struct {
 	*Client
@@ -10,11 +13,7 @@ type Assured struct {
 // NewAssured creates a new assured instance with both server and client
 func NewAssured(opts ...ServerOption) *Assured {
 	s := NewServer(opts...)
-	clientOpts := []ClientOption{WithClientBaseURL(s.URL())}
-	if s.httpClient != nil {
-		clientOpts = append(clientOpts, WithClientHTTPClient(*s.httpClient))
-	}
-	c := NewClient(clientOpts...)
+	c := newAssuredClient(s)
 	return &Assured{
 		Client: c,
 		Server: s,
@@ -24,11 +23,7 @@ func NewAssured(opts ...ServerOption) *Assured {
 // ServeAssured creates and starts a new assured instance with both server and client
 func ServeAssured(ctx context.Context, opts ...ServerOption) (*Assured, error) {
 	s := NewServer(opts...)
-	clientOpts := []ClientOption{WithClientBaseURL(s.URL())}
-	if s.httpClient != nil {
-		clientOpts = append(clientOpts, WithClientHTTPClient(*s.httpClient))
-	}
-	c := NewClient(clientOpts...)
+	c := newAssuredClient(s)
 
 	if err := s.Serve(ctx); err != nil {
 		return nil, err
@@ -39,3 +34,34 @@ func ServeAssured(ctx context.Context, opts ...ServerOption) (*Assured, error) {
 		Server: s,
 	}, nil
 }
+
+// newAssuredClient creates a client for the server, which authenticates with the admin credentials, trusts the
+// server's generated TLS, and speaks HTTP/2 over cleartext when the server does not serve HTTP/1.1
+func newAssuredClient(s *Server) *Client {
+	clientOpts := []ClientOption{WithClientBaseURL(s.AdminURL()), WithClientAdminPrefix(s.adminPrefix)}
+	if s.adminAuth != nil && s.adminAuth.token != "" {
+		clientOpts = append(clientOpts, WithClientToken(s.adminAuth.token))
+	} else if s.adminAuth != nil {
+		clientOpts = append(clientOpts, WithClientBasicAuth(s.adminAuth.username, s.adminAuth.password))
+	}
+	if s.httpClient != nil {
+		httpClient := *s.HTTPClient()
+		if protocols := s.httpProtocols(); protocols != nil && !protocols.HTTP1() && s.schema() == "http" {
+			var transport *http.Transport
+			switch t := httpClient.Transport.(type) {
+			case nil:
+				transport = http.DefaultTransport.(*http.Transport).Clone()
+			case *http.Transport:
+				transport = t.Clone()
+			}
+			// A custom round tripper's protocols cannot be configured
+			if transport != nil {
+				transport.Protocols = &http.Protocols{}
+				transport.Protocols.SetUnencryptedHTTP2(true)
+				httpClient.Transport = transport
+			}
+		}
+		clientOpts = append(clientOpts, WithClientHTTPClient(httpClient))
+	}
+	return NewClient(clientOpts...)
+}
@@ -1,19 +1,37 @@
 package assured
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/coder/websocket"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
 )
 
 func TestAssured(t *testing.T) {
@@ -62,40 +80,44 @@ func TestAssured(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []Record{
 		{
-			Method:  http.MethodGet,
-			Path:    "test/assured",
-			Body:    []byte(`{"calling":"you"}`),
-			Headers: map[string]string{"Content-Length": "17", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"}},
+			Method:   http.MethodGet,
+			Path:     "test/assured",
+			Body:     []byte(`{"calling":"you"}`),
+			Protocol: "HTTP/1.1",
+			Headers:  map[string]string{"Content-Length": "17", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"}},
 		{
-			Method:  http.MethodGet,
-			Path:    "test/assured",
-			Body:    []byte(`{"calling":"again"}`),
-			Headers: map[string]string{"Content-Length": "19", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"}}}, calls)
+			Method:   http.MethodGet,
+			Path:     "test/assured",
+			Body:     []byte(`{"calling":"again"}`),
+			Protocol: "HTTP/1.1",
+			Headers:  map[string]string{"Content-Length": "19", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"}}}, calls)
 
 	calls, err = assured.Verify(t.Context(), http.MethodPost, "teapot/assured")
 	require.NoError(t, err)
 	require.Equal(t, []Record{
 		{
-			Method:  http.MethodPost,
-			Path:    "teapot/assured",
-			Body:    []byte(`{"calling":"here"}`),
-			Headers: map[string]string{"Content-Length": "18", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"}}}, calls)
+			Method:   http.MethodPost,
+			Path:     "teapot/assured",
+			Body:     []byte(`{"calling":"here"}`),
+			Protocol: "HTTP/1.1",
+			Headers:  map[string]string{"Content-Length": "18", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"}}}, calls)
 
 	err = assured.Clear(t.Context(), http.MethodGet, "test/assured")
 	require.NoError(t, err)
 
 	calls, err = assured.Verify(t.Context(), http.MethodGet, "test/assured")
 	require.NoError(t, err)
-	require.Nil(t, calls)
+	require.Empty(t, calls)
 
 	calls, err = assured.Verify(t.Context(), http.MethodPost, "teapot/assured")
 	require.NoError(t, err)
 	require.Equal(t, []Record{
 		{
-			Method:  http.MethodPost,
-			Path:    "teapot/assured",
-			Body:    []byte(`{"calling":"here"}`),
-			Headers: map[string]string{"Content-Length": "18", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"},
+			Method:   http.MethodPost,
+			Path:     "teapot/assured",
+			Body:     []byte(`{"calling":"here"}`),
+			Protocol: "HTTP/1.1",
+			Headers:  map[string]string{"Content-Length": "18", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"},
 		},
 	}, calls)
 
@@ -104,11 +126,11 @@ func TestAssured(t *testing.T) {
 
 	calls, err = assured.Verify(t.Context(), http.MethodGet, "test/assured")
 	require.NoError(t, err)
-	require.Nil(t, calls)
+	require.Empty(t, calls)
 
 	calls, err = assured.Verify(t.Context(), http.MethodPost, "teapot/assured")
 	require.NoError(t, err)
-	require.Nil(t, calls)
+	require.Empty(t, calls)
 }
 
 func TestAssuredTLS(t *testing.T) {
@@ -139,14 +161,164 @@ func TestAssuredTLS(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []Record{
 		{
-			Method:  http.MethodGet,
-			Path:    "test/assured",
-			Body:    []byte(`{"calling":"you"}`),
-			Headers: map[string]string{"Content-Length": "17", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"},
+			Method:   http.MethodGet,
+			Path:     "test/assured",
+			Body:     []byte(`{"calling":"you"}`),
+			Protocol: "HTTP/1.1",
+			Headers:  map[string]string{"Content-Length": "17", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"},
 		},
 	}, calls)
 }
 
+func TestAssuredGeneratedTLS(t *testing.T) {
+	assured, err := ServeAssured(t.Context(), WithGeneratedTLS(), WithMutualTLS("", true))
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(1 * time.Second)
+
+	require.True(t, strings.HasPrefix(assured.URL(), "https://localhost:"))
+	require.NoError(t, assured.Given(t.Context(), *testCall1()))
+
+	resp, err := assured.HTTPClient().Get(assured.URL() + "/assured/ca")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, assured.CACertificate(), body)
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(body))
+	_, err = (&http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}}).Get(assured.URL() + "/test/assured")
+	require.Error(t, err)
+
+	cert, err := assured.ClientCertificate("payments")
+	require.NoError(t, err)
+	resp, err = (&http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: pool, Certificates: []tls.Certificate{cert}},
+	}}).Get(assured.URL() + "/test/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	calls, err := assured.Verify(t.Context(), http.MethodGet, "test/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	require.Equal(t, "CN=payments,O=go-rest-assured", calls[0].ClientCert)
+}
+
+func TestAssuredHTTPAndHTTPS(t *testing.T) {
+	assured, err := ServeAssured(t.Context(), WithGeneratedTLS(), WithMutualTLS("", false), WithHTTPAndHTTPS())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(1 * time.Second)
+
+	require.Equal(t, fmt.Sprintf("http://localhost:%d", assured.Port), assured.URL())
+	require.Equal(t, fmt.Sprintf("https://localhost:%d", assured.TLSPort), assured.TLSURL())
+	require.NoError(t, assured.Given(t.Context(), *testCall1()))
+
+	for _, url := range []string{assured.URL(), assured.TLSURL()} {
+		resp, err := assured.HTTPClient().Get(url + "/test/assured")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, []byte(`{"assured": true}`), body)
+	}
+
+	calls, err := assured.Verify(t.Context(), http.MethodGet, "test/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	require.Empty(t, calls[0].ClientCert)
+	require.Equal(t, "CN=assured-client,O=go-rest-assured", calls[1].ClientCert)
+}
+
+func TestAssuredAdminAuth(t *testing.T) {
+	assured, err := ServeAssured(t.Context(), WithAdminToken("secret"), WithAdminBasicAuth("admin", "password"))
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(1 * time.Second)
+
+	require.NoError(t, assured.Given(t.Context(), *testCall1()))
+
+	anonymous := NewClient(WithClientBaseURL(assured.URL()))
+	require.EqualError(t, anonymous.Given(t.Context(), *testCall2()), "401:unauthorized")
+	_, err = anonymous.Verify(t.Context(), http.MethodGet, "test/assured")
+	require.EqualError(t, err, "401:unauthorized")
+	_, err = anonymous.NewSession(t.Context())
+	require.EqualError(t, err, "401:unauthorized")
+	wrong := NewClient(WithClientBaseURL(assured.URL()), WithClientToken("wrong"))
+	require.EqualError(t, wrong.ClearAll(t.Context()), "401:unauthorized")
+	basic := NewClient(WithClientBaseURL(assured.URL()), WithClientBasicAuth("admin", "password"))
+	require.NoError(t, basic.Given(t.Context(), *testCall2()))
+
+	resp, err := http.Get(assured.URL() + "/assured/health")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = http.Get(assured.URL() + "/test/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = http.Get(assured.URL() + "/assured/v2/goats")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	session, err := assured.NewSession(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, session.Given(t.Context(), *testCall1()))
+	resp, err = http.Get(session.SessionURL() + "/test/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = http.Get(session.SessionURL() + "/assured/verify")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.NoError(t, session.EndSession(t.Context()))
+}
+
+func TestAssuredAdminListener(t *testing.T) {
+	assured, err := ServeAssured(t.Context(), WithAdminPrefix("_admin"), WithAdminListener())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(1 * time.Second)
+
+	require.Equal(t, fmt.Sprintf("http://localhost:%d", assured.AdminPort), assured.AdminURL())
+	require.NotEqual(t, assured.URL(), assured.AdminURL())
+	require.NoError(t, assured.Given(t.Context(), Call{Method: http.MethodPost, Path: "assured/given", Body: "mocked"}))
+
+	resp, err := http.Post(assured.URL()+"/assured/given", "application/json", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, []byte("mocked"), body)
+
+	resp, err = http.Get(assured.URL() + "/_admin/health")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, err = http.Get(assured.AdminURL() + "/_admin/health")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = http.Get(assured.AdminURL() + "/assured/health")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	records, err := assured.Verify(t.Context(), http.MethodPost, "assured/given")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	session, err := assured.NewSession(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, session.Given(t.Context(), *testCall1()))
+	req, err := http.NewRequest(http.MethodGet, assured.URL()+"/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(SessionHeader, session.headers.Get(SessionHeader))
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = http.Get(session.SessionURL() + "/test/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
 func TestAssuredCallbacks(t *testing.T) {
 	httpClient := http.Client{}
 	called := false
@@ -206,6 +378,274 @@ func TestAssuredCallbacks(t *testing.T) {
 	require.True(t, delayCalled, "delayed callback was not hit")
 }
 
+func TestAssuredSequentialCallbacks(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		mu.Lock()
+		events = append(events, string(body))
+		mu.Unlock()
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer testServer.Close()
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	err = assured.Given(t.Context(), Call{
+		Path:                "test/sequence",
+		Method:              http.MethodPost,
+		SequentialCallbacks: true,
+		Callbacks: []Callback{
+			{Method: http.MethodPost, Target: testServer.URL, Delay: 1, Response: []byte("payment.created")},
+			{Method: http.MethodPost, Target: testServer.URL, Response: []byte("payment.succeeded")},
+			{Method: http.MethodPost, Target: testServer.URL + "/fail", Response: []byte("payment.refunded")},
+			{Method: http.MethodPost, Target: testServer.URL, Response: []byte("payment.closed"), RequirePrevious: true},
+		},
+	})
+	require.NoError(t, err)
+
+	resp, err := http.Post(assured.URL()+"/test/sequence", "text/plain", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	time.Sleep(2 * time.Second)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{"payment.created", "payment.succeeded", "payment.refunded"}, events)
+}
+
+func TestAssuredCallbackMutualTLS(t *testing.T) {
+	var subject atomic.Value
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject.Store(r.TLS.PeerCertificates[0].Subject.String())
+	}))
+	testServer.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	testServer.StartTLS()
+	defer testServer.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testServer.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, ca, 0o600))
+
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	err = assured.Trigger(t.Context(), Callback{
+		Method: http.MethodPost,
+		Target: testServer.URL,
+		TLS: &CallbackTLS{
+			CAFile:   caFile,
+			CertFile: "testdata/localhost.pem",
+			KeyFile:  "testdata/localhost-key.pem",
+		},
+	})
+	require.NoError(t, err)
+
+	time.Sleep(500 * time.Millisecond)
+	require.Contains(t, subject.Load(), "mkcert development certificate")
+}
+
+func TestAssuredCallbackTLSClientReused(t *testing.T) {
+	var calls, conns atomic.Int32
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	testServer.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	testServer.StartTLS()
+	defer testServer.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testServer.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, ca, 0o600))
+
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	err = assured.Trigger(t.Context(), Callback{
+		Method:   http.MethodPost,
+		Target:   testServer.URL,
+		Interval: 1,
+		TLS:      &CallbackTLS{CAFile: caFile},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(caFile))
+
+	time.Sleep(2500 * time.Millisecond)
+	require.GreaterOrEqual(t, calls.Load(), int32(3))
+	require.Equal(t, int32(1), conns.Load())
+}
+
+func TestAssuredCallbackBadTLS(t *testing.T) {
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+
+	err = assured.Trigger(t.Context(), Callback{
+		Method: http.MethodPost,
+		Target: "https://localhost/",
+		TLS:    &CallbackTLS{CAFile: "testdata/missing.pem"},
+	})
+
+	require.Error(t, err)
+	require.Equal(t, "400:read callback ca file: open testdata/missing.pem: no such file or directory", err.Error())
+}
+
+func TestAssuredCallbackCancelledOnClose(t *testing.T) {
+	var called atomic.Bool
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called.Store(true)
+	}))
+	defer testServer.Close()
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	time.Sleep(time.Second)
+
+	require.NoError(t, assured.Trigger(t.Context(), Callback{Method: http.MethodPost, Target: testServer.URL, Delay: 1}))
+	require.NoError(t, assured.Close())
+
+	time.Sleep(1500 * time.Millisecond)
+	require.False(t, called.Load(), "pending callback should be cancelled when the server closes")
+}
+
+func TestAssuredTrigger(t *testing.T) {
+	var hits atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, []byte(`{"event":"pushed"}`), body)
+		require.Equal(t, "partner", r.Header.Get("x-source"))
+		hits.Add(1)
+	}))
+	defer testServer.Close()
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	time.Sleep(time.Second)
+
+	err = assured.Trigger(t.Context(), Callback{
+		Method:   http.MethodPost,
+		Target:   testServer.URL,
+		Interval: 1,
+		Response: []byte(`{"event":"pushed"}`),
+		Headers:  map[string]string{"x-source": "partner"},
+	})
+	require.NoError(t, err)
+
+	time.Sleep(2500 * time.Millisecond)
+	require.GreaterOrEqual(t, hits.Load(), int32(3), "periodic callback was not repeated")
+
+	require.NoError(t, assured.Close())
+	time.Sleep(500 * time.Millisecond)
+	stopped := hits.Load()
+	time.Sleep(1500 * time.Millisecond)
+	require.Equal(t, stopped, hits.Load(), "periodic callback should stop when the server closes")
+}
+
+func TestAssuredCancelCallback(t *testing.T) {
+	var hits atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer testServer.Close()
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	callback, err := assured.TriggerCallback(t.Context(), Callback{Method: http.MethodPost, Target: testServer.URL, Interval: 1})
+	require.NoError(t, err)
+	require.NotEmpty(t, callback.ID)
+
+	time.Sleep(1500 * time.Millisecond)
+	require.NoError(t, assured.CancelCallback(t.Context(), callback.ID))
+	stopped := hits.Load()
+	require.GreaterOrEqual(t, stopped, int32(2))
+	time.Sleep(1500 * time.Millisecond)
+	require.Equal(t, stopped, hits.Load(), "periodic callback should stop when cancelled")
+
+	err = assured.CancelCallback(t.Context(), callback.ID)
+	require.Error(t, err)
+	require.Equal(t, "404:assured callback not found", err.Error())
+}
+
+func TestAssuredCancelCallbackOfStub(t *testing.T) {
+	var called atomic.Bool
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called.Store(true)
+	}))
+	defer testServer.Close()
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	stub, err := assured.Stub(t.Context(), Call{
+		Method:    http.MethodGet,
+		Path:      "test/assured",
+		Callbacks: []Callback{{Method: http.MethodPost, Target: testServer.URL, Delay: 1}},
+	})
+	require.NoError(t, err)
+	resp, err := http.Get(assured.URL() + "/test/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	err = assured.CancelCallback(t.Context(), stub.ID)
+	require.Error(t, err)
+	require.Equal(t, "404:assured callback not found", err.Error())
+
+	time.Sleep(1500 * time.Millisecond)
+	require.True(t, called.Load(), "a stub's callbacks should not be cancelled as a triggered callback")
+}
+
+func TestAssuredCallbackCancelledOnClear(t *testing.T) {
+	var called atomic.Bool
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called.Store(true)
+	}))
+	defer testServer.Close()
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	require.NoError(t, assured.Given(t.Context(), Call{
+		Method:    http.MethodGet,
+		Path:      "test/assured",
+		Callbacks: []Callback{{Method: http.MethodPost, Target: testServer.URL, Delay: 1}},
+	}))
+	resp, err := http.Get(assured.URL() + "/test/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, assured.ClearAll(t.Context()))
+
+	time.Sleep(1500 * time.Millisecond)
+	require.False(t, called.Load(), "pending callback should be cancelled when its stub is cleared")
+}
+
+func TestAssuredTriggerMissingTarget(t *testing.T) {
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+
+	err = assured.Trigger(t.Context(), Callback{Method: http.MethodPost})
+
+	require.Error(t, err)
+	require.Equal(t, "400:cannot stub callback without target", err.Error())
+}
+
 func TestAssuredClose(t *testing.T) {
 	assured, err := ServeAssured(t.Context())
 	require.NoError(t, err)
@@ -294,12 +734,12 @@ func TestAssuredBadRequestFailure(t *testing.T) {
 	err = assured.Given(t.Context(), Call{Method: "\"", Path: "goat/path"})
 
 	require.Error(t, err)
-	require.Equal(t, `net/http: invalid method "\""`, err.Error())
+	require.Equal(t, `400:net/http: invalid method "\""`, err.Error())
 
 	err = assured.Given(t.Context(), Call{Method: "\"", Path: "goat/path", Response: []byte("goats among men")})
 
 	require.Error(t, err)
-	require.Equal(t, `net/http: invalid method "\""`, err.Error())
+	require.Equal(t, `400:net/http: invalid method "\""`, err.Error())
 
 	calls, err := assured.Verify(t.Context(), "\"", "goat/path")
 
@@ -316,7 +756,7 @@ func TestAssuredBadRequestFailure(t *testing.T) {
 	err = assured.ClearAll(t.Context())
 
 	require.Error(t, err)
-	require.Equal(t, `parse "http://localhost:-1/assured/clearall": invalid port ":-1" after host`, err.Error())
+	require.Equal(t, `parse "http://localhost:-1/assured/v2/snapshot": invalid port ":-1" after host`, err.Error())
 }
 
 func TestAssuredVerifyHttpClientFailure(t *testing.T) {
@@ -368,3 +808,916 @@ func TestAssuredPathSanitization(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, http.StatusAccepted, resp.StatusCode)
 }
+
+func TestAssuredStubManagement(t *testing.T) {
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	stub1, err := assured.Stub(t.Context(), *testCall1())
+	require.NoError(t, err)
+	require.NotEmpty(t, stub1.ID)
+	stub2, err := assured.Stub(t.Context(), *testCall2())
+	require.NoError(t, err)
+	require.NotEqual(t, stub1.ID, stub2.ID)
+	stub3, err := assured.Stub(t.Context(), *testCall3())
+	require.NoError(t, err)
+
+	stubs, err := assured.Stubs(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []Call{stub1, stub2, stub3}, stubs)
+
+	stub, err := assured.GetStub(t.Context(), stub2.ID)
+	require.NoError(t, err)
+	require.Equal(t, stub2, stub)
+
+	updated := *testCall2()
+	updated.StatusCode = http.StatusAccepted
+	stub, err = assured.UpdateStub(t.Context(), stub2.ID, updated)
+	require.NoError(t, err)
+	updated.ID = stub2.ID
+	require.Equal(t, updated, stub)
+
+	require.NoError(t, assured.DeleteStub(t.Context(), stub1.ID))
+
+	stubs, err = assured.Stubs(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []Call{updated, stub3}, stubs)
+
+	resp, err := http.Get(assured.URL() + "/test/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	_, err = assured.GetStub(t.Context(), stub1.ID)
+	require.Error(t, err)
+	require.Equal(t, "404:assured call not found", err.Error())
+
+	_, err = assured.UpdateStub(t.Context(), stub1.ID, updated)
+	require.Error(t, err)
+	require.Equal(t, "404:assured call not found", err.Error())
+
+	err = assured.DeleteStub(t.Context(), stub1.ID)
+	require.Error(t, err)
+	require.Equal(t, "404:assured call not found", err.Error())
+}
+
+func TestAssuredUpdateStubChangesKey(t *testing.T) {
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	stub, err := assured.Stub(t.Context(), *testCall1())
+	require.NoError(t, err)
+
+	stub, err = assured.UpdateStub(t.Context(), stub.ID, Call{Method: http.MethodDelete, Path: "/moved/"})
+	require.NoError(t, err)
+	require.Equal(t, "DELETE:moved", stub.Key())
+
+	resp, err := http.Get(assured.URL() + "/test/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodDelete, assured.URL()+"/moved", nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAssuredSwapStubs(t *testing.T) {
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	kept, err := assured.Stub(t.Context(), *testCall1())
+	require.NoError(t, err)
+	swapped, err := assured.Stub(t.Context(), *testCall2())
+	require.NoError(t, err)
+
+	_, err = assured.SwapStubs(t.Context(), []string{swapped.ID}, *testCall3(), Call{Method: "\"", Path: "rejected"})
+	require.Error(t, err)
+	stubs, err := assured.Stubs(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []Call{kept, swapped}, stubs)
+
+	added, err := assured.SwapStubs(t.Context(), []string{swapped.ID, "unknown"}, *testCall3())
+	require.NoError(t, err)
+	require.Len(t, added, 1)
+	require.NotEmpty(t, added[0].ID)
+	stubs, err = assured.Stubs(t.Context())
+	require.NoError(t, err)
+	require.ElementsMatch(t, []Call{kept, added[0]}, stubs)
+}
+
+func TestAssuredAdminV2(t *testing.T) {
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(1 * time.Second)
+
+	do := func(method, path string, body io.Reader) *http.Response {
+		req, err := http.NewRequest(method, assured.URL()+path, body)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	requireProblem := func(resp *http.Response, status int, detail string) {
+		require.Equal(t, status, resp.StatusCode)
+		require.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+		var problem Problem
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+		require.Equal(t, Problem{Title: http.StatusText(status), Status: status, Detail: detail}, problem)
+	}
+
+	resp := do(http.MethodPost, "/assured/v2/stubs", strings.NewReader(`{"method":"GET","path":"test/assured","body":"assured"}`))
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var stub Call
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stub))
+	require.Equal(t, "/assured/v2/stubs/"+stub.ID, resp.Header.Get("Location"))
+	resp = do(http.MethodGet, resp.Header.Get("Location"), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = do(http.MethodGet, "/assured/given", strings.NewReader(`{"method":"GET","path":"test/legacy"}`))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = do(http.MethodPost, "/assured/verify", strings.NewReader(`{"method":"GET","path":"test/assured"}`))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = do(http.MethodGet, "/assured/clearall", nil)
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	require.Equal(t, http.MethodPost, resp.Header.Get("Allow"))
+	var apiErr APIError
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&apiErr))
+	require.Equal(t, APIError{"method GET is not allowed"}, apiErr)
+	resp = do(http.MethodOptions, "/assured/v2/stubs", nil)
+	require.Equal(t, "DELETE, GET, PATCH, POST, PUT", resp.Header.Get("Allow"))
+	requireProblem(resp, http.StatusMethodNotAllowed, "method OPTIONS is not allowed")
+	resp = do(http.MethodGet, "/assured/v2/stubs/missing", nil)
+	requireProblem(resp, http.StatusNotFound, "assured call not found")
+	resp = do(http.MethodPost, "/assured/v2/stubs", strings.NewReader(`{"method":"\""}`))
+	requireProblem(resp, http.StatusBadRequest, `net/http: invalid method "\""`)
+	resp = do(http.MethodGet, "/assured/v2/goats", nil)
+	requireProblem(resp, http.StatusNotFound, "unknown resource /assured/v2/goats")
+
+	resp = do(http.MethodGet, "/test/assured", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = do(http.MethodGet, "/assured/v2/requests?path=test/assured", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var records []Record
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&records))
+	require.Len(t, records, 1)
+	resp = do(http.MethodDelete, "/assured/v2/requests?method=GET", nil)
+	requireProblem(resp, http.StatusBadRequest, "method and path must be set together")
+	resp = do(http.MethodDelete, "/assured/v2/snapshot?path=test/assured", nil)
+	requireProblem(resp, http.StatusBadRequest, "method and path must be set together")
+	resp = do(http.MethodDelete, "/assured/v2/requests", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = do(http.MethodGet, "/assured/v2/stubs?method=GET&path=test/assured", nil)
+	var stubs []Call
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stubs))
+	require.Len(t, stubs, 1)
+
+	resp = do(http.MethodPost, "/assured/v2/sessions", nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	location := resp.Header.Get("Location")
+	resp = do(http.MethodDelete, location, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = do(http.MethodDelete, location, nil)
+	requireProblem(resp, http.StatusNotFound, "unknown session "+strings.TrimPrefix(location, "/assured/v2/sessions/"))
+}
+
+func TestAssuredSnapshotRestore(t *testing.T) {
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	require.NoError(t, assured.Given(t.Context(), *testCall1(), *testCall2()))
+	resp, err := http.Get(assured.URL() + "/test/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	baseline, err := assured.Snapshot(t.Context())
+	require.NoError(t, err)
+	require.Len(t, baseline.Calls, 2)
+	require.Len(t, baseline.Records, 1)
+
+	require.NoError(t, assured.ClearAll(t.Context()))
+	require.NoError(t, assured.Given(t.Context(), *testCall3()))
+
+	require.NoError(t, assured.Restore(t.Context(), baseline))
+
+	restored, err := assured.Snapshot(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, baseline, restored)
+
+	resp, err = http.Get(assured.URL() + "/test/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, err = http.Post(assured.URL()+"/teapot/assured", "text/plain", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	records, err := assured.Verify(t.Context(), http.MethodGet, "test/assured")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+}
+
+func TestAssuredRestoreInvalid(t *testing.T) {
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+
+	err = assured.Restore(t.Context(), Snapshot{Calls: []Call{{Method: "\"", Path: "goat/path"}}})
+
+	require.Error(t, err)
+	require.Equal(t, `400:net/http: invalid method "\""`, err.Error())
+}
+
+func TestAssuredRequestValidation(t *testing.T) {
+	spec, err := LoadOpenAPI("testdata/petstore.yaml")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		mode       ValidationMode
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "reject",
+			mode:       ValidationReject,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"title":"Request does not conform to the OpenAPI document","status":400,"errors":["body.tag: must be one of [dog cat]"]}` + "\n",
+		},
+		{
+			name:       "record",
+			mode:       ValidationRecord,
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id":7,"name":"Rex","tag":"dog"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assured, err := ServeAssured(t.Context(), WithRequestValidation(spec, tt.mode))
+			require.NoError(t, err)
+			defer func() { _ = assured.Close() }()
+			time.Sleep(time.Second)
+
+			calls, err := spec.Calls()
+			require.NoError(t, err)
+			require.NoError(t, assured.Given(t.Context(), calls...))
+
+			resp, err := http.Post(assured.URL()+"/v1/pets", "application/json", strings.NewReader(`{"name":"Rex","tag":"fish"}`))
+			require.NoError(t, err)
+			require.Equal(t, tt.wantStatus, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantBody, string(body))
+
+			resp, err = http.Post(assured.URL()+"/v1/pets", "application/json", strings.NewReader(`{"name":"Rex","tag":"dog"}`))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+			records, err := assured.Verify(t.Context(), http.MethodPost, "v1/pets")
+			require.NoError(t, err)
+			require.Len(t, records, 2)
+			require.Equal(t, []string{"body.tag: must be one of [dog cat]"}, records[0].Violations)
+			require.Empty(t, records[1].Violations)
+		})
+	}
+}
+
+func TestAssuredStubValidation(t *testing.T) {
+	spec, err := LoadOpenAPI("testdata/petstore.yaml")
+	require.NoError(t, err)
+	assured, err := ServeAssured(t.Context(), WithStubValidation(spec))
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	calls, err := spec.Calls()
+	require.NoError(t, err)
+	require.NoError(t, assured.Given(t.Context(), calls...))
+
+	err = assured.Given(t.Context(), Call{Method: http.MethodGet, Path: "v1/pets/7", Response: []byte(`{"id":7}`)})
+	require.Error(t, err)
+	require.Equal(t, "400:Call does not conform to the OpenAPI document: response.name: is required", err.Error())
+
+	stub, err := assured.Stub(t.Context(), Call{Method: http.MethodGet, Path: "v1/pets/8", Response: []byte(`{"id":8,"name":"Fido"}`)})
+	require.NoError(t, err)
+
+	_, err = assured.UpdateStub(t.Context(), stub.ID, Call{Method: http.MethodGet, Path: "v1/pets/8", StatusCode: http.StatusTeapot})
+	require.Error(t, err)
+	require.Equal(t, "400:Call does not conform to the OpenAPI document: status_code: 418 is not a documented response", err.Error())
+
+	err = assured.Restore(t.Context(), Snapshot{Calls: []Call{{Method: http.MethodGet, Path: "v1/pets/9", Response: []byte(`{"id":9}`)}}})
+	require.Error(t, err)
+	require.Equal(t, "400:Call does not conform to the OpenAPI document: response.name: is required", err.Error())
+	stubs, err := assured.Stubs(t.Context())
+	require.NoError(t, err)
+	require.Len(t, stubs, len(calls)+1)
+}
+
+func TestAssuredPathTemplate(t *testing.T) {
+	spec, err := LoadOpenAPI("testdata/petstore.yaml")
+	require.NoError(t, err)
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	calls, err := spec.Calls()
+	require.NoError(t, err)
+	require.NoError(t, assured.Given(t.Context(), calls...))
+	require.NoError(t, assured.Given(t.Context(), Call{Method: http.MethodGet, Path: "v1/pets/7", Body: "Rex"}))
+
+	for _, tt := range []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{path: "/v1/pets/1", wantStatus: http.StatusOK, wantBody: `{"born":"2024-01-01","id":0,"name":"string","tag":"dog"}`},
+		{path: "/v1/pets/2", wantStatus: http.StatusOK, wantBody: `{"born":"2024-01-01","id":0,"name":"string","tag":"dog"}`},
+		{path: "/v1/pets/7", wantStatus: http.StatusOK, wantBody: "Rex"},
+		{path: "/v1/pets/2/photo", wantStatus: http.StatusOK, wantBody: "A very good dog"},
+		{path: "/v1/pets/2/toys", wantStatus: http.StatusNotFound, wantBody: `{"error":"no assured calls"}` + "\n"},
+	} {
+		resp, err := http.Get(assured.URL() + tt.path)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, tt.wantStatus, resp.StatusCode, tt.path)
+		require.Equal(t, tt.wantBody, string(body), tt.path)
+	}
+
+	records, err := assured.Verify(t.Context(), http.MethodGet, "v1/pets/2")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+}
+
+func TestAssuredReplaceStubs(t *testing.T) {
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	require.NoError(t, assured.Given(t.Context(), *testCall1()))
+	resp, err := http.Get(assured.URL() + "/test/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = assured.ReplaceStubs(t.Context(), *testCall3(), Call{Method: "\"", Path: "goat/path"})
+	require.Error(t, err)
+	require.Equal(t, `400:net/http: invalid method "\""`, err.Error())
+
+	stubs, err := assured.ReplaceStubs(t.Context(), *testCall3())
+	require.NoError(t, err)
+	require.Len(t, stubs, 1)
+	require.NotEmpty(t, stubs[0].ID)
+
+	all, err := assured.Stubs(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, stubs, all)
+
+	records, err := assured.Verify(t.Context(), http.MethodGet, "test/assured")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	stubs, err = assured.ReplaceStubs(t.Context())
+	require.NoError(t, err)
+	require.Empty(t, stubs)
+}
+
+func TestAssuredExplicitBodies(t *testing.T) {
+	callbackBody := make(chan []byte, 1)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		callbackBody <- body
+	}))
+	defer testServer.Close()
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	require.NoError(t, assured.Given(t.Context(),
+		Call{Path: "body", Body: "testdata/assured.json"},
+		Call{Path: "json", JSONBody: json.RawMessage(`{"assured": true}`)},
+		Call{Path: "base64", Base64Body: []byte{0xff, 0xd8, 0xff}},
+		Call{
+			Path:      "file",
+			BodyFile:  "testdata/responses/account.json",
+			Callbacks: []Callback{{Method: http.MethodPost, Target: testServer.URL, JSONBody: json.RawMessage(`{"done":true}`)}},
+		},
+	))
+
+	for path, want := range map[string][]byte{
+		"body":   []byte("testdata/assured.json"),
+		"json":   []byte(`{"assured":true}`),
+		"base64": {0xff, 0xd8, 0xff},
+		"file":   []byte(`{"accounts": [{"id": "acc_1"}]}`),
+	} {
+		resp, err := http.Get(assured.URL() + "/" + path)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, want, body, path)
+	}
+	require.Equal(t, []byte(`{"done":true}`), <-callbackBody)
+
+	err = assured.Given(t.Context(), Call{Path: "both", Body: "body", Response: []byte("response")})
+	require.Error(t, err)
+	require.Equal(t, "400:only one of response, body, json_body, base64_body or body_file may be set", err.Error())
+
+	err = assured.Given(t.Context(), Call{Path: "missing", BodyFile: "testdata/missing.json"})
+	require.Error(t, err)
+	require.Equal(t, "400:invalid body_file: stat testdata/missing.json: no such file or directory", err.Error())
+}
+
+func TestAssuredBodyFileStreaming(t *testing.T) {
+	image, err := os.ReadFile("testdata/image.jpg")
+	require.NoError(t, err)
+	page := filepath.Join(t.TempDir(), "page")
+	require.NoError(t, os.WriteFile(page, []byte("<html><body>assured</body></html>"), 0o600))
+
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	require.NoError(t, assured.Given(t.Context(),
+		Call{Path: "image", BodyFile: "testdata/image.jpg"},
+		Call{Path: "page", StatusCode: http.StatusNotFound, BodyFile: page},
+	))
+
+	resp, err := http.Get(assured.URL() + "/image")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "image/jpeg", resp.Header.Get("Content-Type"))
+	require.Equal(t, strconv.Itoa(len(image)), resp.Header.Get("Content-Length"))
+	require.Equal(t, "bytes", resp.Header.Get("Accept-Ranges"))
+	require.Equal(t, image, body)
+
+	req, err := http.NewRequest(http.MethodGet, assured.URL()+"/image", nil)
+	require.NoError(t, err)
+	req.Header.Set("Range", "bytes=10-19")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	require.Equal(t, fmt.Sprintf("bytes 10-19/%d", len(image)), resp.Header.Get("Content-Range"))
+	require.Equal(t, image[10:20], body)
+
+	resp, err = http.Get(assured.URL() + "/page")
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	require.Equal(t, "<html><body>assured</body></html>", string(body))
+}
+
+func TestAssuredStreaming(t *testing.T) {
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	require.NoError(t, assured.Given(t.Context(), Call{
+		Path:        "completions",
+		Method:      http.MethodPost,
+		ChunkFormat: ChunkFormatSSE,
+		Chunks: []Chunk{
+			{Event: "delta", JSONBody: json.RawMessage(`{"text":"Hello"}`)},
+			{Event: "delta", JSONBody: json.RawMessage(`{"text":" world"}`), DelayMS: 500},
+			{Event: "done", Body: "[DONE]", DelayMS: 100},
+		},
+	}))
+
+	start := time.Now()
+	resp, err := http.Post(assured.URL()+"/completions", "application/json", nil)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	require.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "event: delta\n", line)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+
+	rest, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 600*time.Millisecond)
+	require.Equal(t, "data: {\"text\":\"Hello\"}\n\nevent: delta\ndata: {\"text\":\" world\"}\n\nevent: done\ndata: [DONE]\n\n", string(rest))
+
+	err = assured.Given(t.Context(), Call{Path: "both", Body: "body", Chunks: []Chunk{{Body: "chunk"}}})
+	require.Error(t, err)
+	require.Equal(t, "400:chunks cannot be set with a response body", err.Error())
+
+	err = assured.Given(t.Context(), Call{Path: "format", ChunkFormat: "xml", Chunks: []Chunk{{Body: "chunk"}}})
+	require.Error(t, err)
+	require.Equal(t, "400:unknown chunk_format \"xml\"", err.Error())
+}
+
+func TestAssuredWebSocket(t *testing.T) {
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	require.NoError(t, assured.Given(t.Context(), Call{
+		Path: "feed",
+		WebSocket: &WebSocket{
+			OnConnect: []WebSocketMessage{{JSON: json.RawMessage(`{"type":"welcome"}`)}},
+			Replies: []WebSocketReply{
+				{Match: `"type":"ping"`, Messages: []WebSocketMessage{{Text: "pong", DelayMS: 50}}},
+				{Match: `^bye$`, Close: &WebSocketClose{Code: 4001, Reason: "goodbye"}},
+				{Messages: []WebSocketMessage{{Binary: []byte{0x01, 0x02}}}},
+			},
+		},
+	}))
+
+	conn, _, err := websocket.Dial(t.Context(), strings.Replace(assured.URL(), "http", "ws", 1)+"/feed", nil)
+	require.NoError(t, err)
+	defer func() { _ = conn.CloseNow() }()
+
+	typ, data, err := conn.Read(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, websocket.MessageText, typ)
+	require.Equal(t, `{"type":"welcome"}`, string(data))
+
+	require.NoError(t, conn.Write(t.Context(), websocket.MessageText, []byte(`{"type":"ping"}`)))
+	_, data, err = conn.Read(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, "pong", string(data))
+
+	require.NoError(t, conn.Write(t.Context(), websocket.MessageText, []byte("anything")))
+	typ, data, err = conn.Read(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, websocket.MessageBinary, typ)
+	require.Equal(t, []byte{0x01, 0x02}, data)
+
+	require.NoError(t, conn.Write(t.Context(), websocket.MessageText, []byte("bye")))
+	_, _, err = conn.Read(t.Context())
+	require.Equal(t, websocket.StatusCode(4001), websocket.CloseStatus(err))
+
+	received, err := assured.Verify(t.Context(), WebSocketReceived, "feed")
+	require.NoError(t, err)
+	require.Equal(t, []Record{
+		{Path: "feed", Method: WebSocketReceived, Body: []byte(`{"type":"ping"}`)},
+		{Path: "feed", Method: WebSocketReceived, Body: []byte("anything")},
+		{Path: "feed", Method: WebSocketReceived, Body: []byte("bye")},
+	}, received)
+	sent, err := assured.Verify(t.Context(), WebSocketSent, "feed")
+	require.NoError(t, err)
+	require.Len(t, sent, 3)
+
+	err = assured.Given(t.Context(), Call{Path: "bad", WebSocket: &WebSocket{Close: &WebSocketClose{Code: 42}}})
+	require.Error(t, err)
+	require.Equal(t, "400:invalid websocket close code 42", err.Error())
+}
+
+func TestAssuredGRPC(t *testing.T) {
+	files, err := LoadDescriptorSet("testdata/health.binpb")
+	require.NoError(t, err)
+	assured, err := ServeAssured(t.Context(), WithGRPC(files))
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	require.NoError(t, assured.Given(t.Context(),
+		Call{
+			Method:   GRPCMethod,
+			Path:     "grpc.health.v1.Health/Check",
+			Headers:  map[string]string{"x-assured": "true"},
+			JSONBody: json.RawMessage(`{"status":"SERVING"}`),
+		},
+		Call{
+			Method: GRPCMethod,
+			Path:   "grpc.health.v1.Health/Check",
+			GRPC:   &GRPCStatus{Code: int(codes.NotFound), Message: "unknown service", Trailers: map[string]string{"x-reason": "missing"}},
+		},
+		Call{
+			Method: GRPCMethod,
+			Path:   "/grpc.health.v1.Health/Watch",
+			Chunks: []Chunk{{Body: `{"status":"NOT_SERVING"}`}, {Body: `{"status":"SERVING"}`, DelayMS: 50}},
+		},
+	))
+
+	conn, err := grpc.NewClient(assured.GRPCAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	client := healthpb.NewHealthClient(conn)
+
+	var header, trailer metadata.MD
+	resp, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "payments"}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+	require.Equal(t, []string{"true"}, header.Get("x-assured"))
+
+	_, err = client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "refunds"}, grpc.Trailer(&trailer))
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Equal(t, "unknown service", status.Convert(err).Message())
+	require.Equal(t, []string{"missing"}, trailer.Get("x-reason"))
+
+	watch, err := client.Watch(t.Context(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	statuses := []healthpb.HealthCheckResponse_ServingStatus{}
+	for {
+		resp, err := watch.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		statuses = append(statuses, resp.GetStatus())
+	}
+	require.Equal(t, []healthpb.HealthCheckResponse_ServingStatus{healthpb.HealthCheckResponse_NOT_SERVING, healthpb.HealthCheckResponse_SERVING}, statuses)
+
+	_, err = healthpb.NewHealthClient(conn).List(t.Context(), &healthpb.HealthListRequest{})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+
+	records, err := assured.Verify(t.Context(), GRPCMethod, "grpc.health.v1.Health/Check")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.JSONEq(t, `{"service":"payments"}`, string(records[0].Body))
+	require.Equal(t, "application/grpc", records[0].Headers["content-type"])
+
+	reflection, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, reflection.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	listed, err := reflection.Recv()
+	require.NoError(t, err)
+	services := []string{}
+	for _, service := range listed.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	require.Contains(t, services, "grpc.health.v1.Health")
+}
+
+func TestAssuredGraphQL(t *testing.T) {
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	require.NoError(t, assured.Given(t.Context(),
+		Call{
+			Method:  http.MethodPost,
+			Path:    "graphql",
+			GraphQL: &GraphQL{OperationName: "GetPet", Variables: map[string]json.RawMessage{"id": json.RawMessage(`"7"`)}, Data: json.RawMessage(`{"pet":{"name":"Fido"}}`)},
+		},
+		Call{
+			Method:  http.MethodPost,
+			Path:    "graphql",
+			GraphQL: &GraphQL{OperationName: "GetPet", Errors: json.RawMessage(`[{"message":"pet not found"}]`)},
+		},
+		Call{
+			Method:  http.MethodPost,
+			Path:    "graphql",
+			GraphQL: &GraphQL{Query: "mutation { adopt { id } }", Data: json.RawMessage(`{"adopt":{"id":"1"}}`)},
+		},
+	))
+
+	for _, tt := range []struct {
+		request string
+		want    string
+	}{
+		{request: `{"query": "mutation { adopt { id } }"}`, want: `{"data":{"adopt":{"id":"1"}}}`},
+		{request: `{"query": "query GetPet($id: ID!) { pet(id: $id) { name } }", "variables": {"id": "8"}}`, want: `{"errors":[{"message":"pet not found"}]}`},
+		{request: `{"query": "query GetPet($id: ID!) { pet(id: $id) { name } }", "variables": {"id": "7"}}`, want: `{"data":{"pet":{"name":"Fido"}}}`},
+	} {
+		resp, err := http.Post(assured.URL()+"/graphql", "application/json", strings.NewReader(tt.request))
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		require.Equal(t, tt.want, string(body))
+	}
+
+	resp, err := http.Post(assured.URL()+"/graphql", "application/json", strings.NewReader(`{"query": "query ListPets { pets { id } }"}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	records, err := assured.VerifyGraphQL(t.Context(), http.MethodPost, "graphql", "GetPet")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	records, err = assured.Verify(t.Context(), http.MethodPost, "graphql")
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	err = assured.Given(t.Context(), Call{Path: "graphql", Body: "body", GraphQL: &GraphQL{Data: json.RawMessage(`{}`)}})
+	require.Error(t, err)
+	require.Equal(t, "400:graphql data and errors cannot be set with a response body", err.Error())
+}
+
+func TestAssuredGraphQLPrecedence(t *testing.T) {
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	require.NoError(t, assured.Given(t.Context(),
+		Call{Method: http.MethodPost, Path: "graphql", Body: "generic"},
+		Call{Method: http.MethodPost, Path: "graphql", GraphQL: &GraphQL{OperationName: "GetPet", Data: json.RawMessage(`"specific"`)}},
+	))
+
+	for _, tt := range []struct {
+		request string
+		want    string
+	}{
+		{request: `{"query": "query GetPet { pet { name } }"}`, want: `{"data":"specific"}`},
+		{request: `{"query": "query GetPet { pet { name } }"}`, want: `{"data":"specific"}`},
+		{request: `{"query": "query ListPets { pets { id } }"}`, want: "generic"},
+		{request: `{"query": "query GetPet { pet { name } }"}`, want: `{"data":"specific"}`},
+	} {
+		resp, err := http.Post(assured.URL()+"/graphql", "application/json", strings.NewReader(tt.request))
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, string(body))
+	}
+}
+
+func TestAssuredServices(t *testing.T) {
+	assured, err := ServeAssured(t.Context(),
+		WithService(Service{Name: "payments", Host: "payments.local"}),
+		WithService(Service{Name: "refunds", PathPrefix: "/refunds/"}),
+		WithService(Service{Name: "ledger", Listen: true}),
+	)
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	require.NoError(t, assured.Given(t.Context(), Call{Path: "status", Body: "default"}))
+	for _, name := range []string{"payments", "refunds", "ledger"} {
+		require.NoError(t, assured.Service(name).Given(t.Context(), Call{Path: "status", Body: name}))
+	}
+	require.Equal(t, assured.URL()+"/refunds", assured.ServiceURL("refunds"))
+	require.NotEqual(t, assured.URL(), assured.ServiceURL("ledger"))
+	require.Empty(t, assured.ServiceURL("missing"))
+
+	get := func(url, host string) string {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+	require.Equal(t, "default", get(assured.URL()+"/status", ""))
+	require.Equal(t, "payments", get(assured.URL()+"/status", "payments.local:80"))
+	require.Equal(t, "refunds", get(assured.ServiceURL("refunds")+"/status", ""))
+	require.Equal(t, "ledger", get(assured.ServiceURL("ledger")+"/status", ""))
+
+	records, err := assured.Verify(t.Context(), http.MethodGet, "status")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	records, err = assured.Service("refunds").Verify(t.Context(), http.MethodGet, "status")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	require.NoError(t, assured.Service("payments").ClearAll(t.Context()))
+	stubs, err := assured.Service("payments").Stubs(t.Context())
+	require.NoError(t, err)
+	require.Empty(t, stubs)
+	stubs, err = assured.Stubs(t.Context())
+	require.NoError(t, err)
+	require.Len(t, stubs, 1)
+
+	_, err = assured.Service("missing").Stubs(t.Context())
+	require.Error(t, err)
+	require.Equal(t, "404:unknown service missing", err.Error())
+}
+
+func TestAssuredSessionTTL(t *testing.T) {
+	assured, err := ServeAssured(t.Context(), WithSessionTTL(500*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = assured.Close() })
+	time.Sleep(time.Second)
+
+	idle, err := assured.NewSession(t.Context())
+	require.NoError(t, err)
+	active, err := assured.NewSession(t.Context())
+	require.NoError(t, err)
+	for range 3 {
+		time.Sleep(300 * time.Millisecond)
+		_, err = active.Stubs(t.Context())
+		require.NoError(t, err)
+	}
+
+	_, err = idle.Stubs(t.Context())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "404:unknown session")
+
+	router := assured.server.Handler.(*router)
+	router.Lock()
+	require.Len(t, router.sessions, 1)
+	router.Unlock()
+}
+
+func TestAssuredSessions(t *testing.T) {
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = assured.Close() })
+	time.Sleep(time.Second)
+	require.NoError(t, assured.Given(t.Context(), Call{Path: "status", Body: "default"}))
+
+	for _, name := range []string{"first", "second", "third"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			session, err := assured.NewSession(t.Context())
+			require.NoError(t, err)
+			require.NoError(t, session.Given(t.Context(), Call{Path: "status", Body: name}))
+
+			resp, err := http.Get(session.SessionURL() + "/status")
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, name, string(body))
+
+			req, err := http.NewRequest(http.MethodGet, assured.URL()+"/status", nil)
+			require.NoError(t, err)
+			req.Header.Set(SessionHeader, session.headers.Get(SessionHeader))
+			resp, err = http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			body, err = io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, name, string(body))
+
+			records, err := session.Verify(t.Context(), http.MethodGet, "status")
+			require.NoError(t, err)
+			require.Len(t, records, 2)
+
+			require.NoError(t, session.ClearAll(t.Context()))
+			stubs, err := session.Stubs(t.Context())
+			require.NoError(t, err)
+			require.Empty(t, stubs)
+
+			require.NoError(t, session.EndSession(t.Context()))
+			_, err = session.Stubs(t.Context())
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "404:unknown session")
+		})
+	}
+
+	t.Cleanup(func() {
+		stubs, err := assured.Stubs(context.Background())
+		require.NoError(t, err)
+		require.Len(t, stubs, 1)
+		records, err := assured.Verify(context.Background(), http.MethodGet, "status")
+		require.NoError(t, err)
+		require.Empty(t, records)
+	})
+}
+
+func TestAssuredProtocols(t *testing.T) {
+	h2c, err := ServeAssured(t.Context(), WithProtocols(ProtocolH2C))
+	require.NoError(t, err)
+	defer func() { _ = h2c.Close() }()
+	http1, err := ServeAssured(t.Context(), WithProtocols(ProtocolHTTP1), WithTLS("testdata/localhost.pem", "testdata/localhost-key.pem"),
+		WithHTTPClient(http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}))
+	require.NoError(t, err)
+	defer func() { _ = http1.Close() }()
+	time.Sleep(time.Second)
+
+	require.NoError(t, h2c.Given(t.Context(), Call{Path: "status"}))
+	require.NoError(t, http1.Given(t.Context(), Call{Path: "status"}))
+
+	h2cTransport := &http.Transport{Protocols: &http.Protocols{}}
+	h2cTransport.Protocols.SetUnencryptedHTTP2(true)
+	resp, err := (&http.Client{Transport: h2cTransport}).Get(h2c.URL() + "/status")
+	require.NoError(t, err)
+	require.Equal(t, "HTTP/2.0", resp.Proto)
+	_, err = http.Get(h2c.URL() + "/status")
+	require.Error(t, err)
+
+	h2Transport := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, ForceAttemptHTTP2: true}
+	resp, err = (&http.Client{Transport: h2Transport}).Get(http1.URL() + "/status")
+	require.NoError(t, err)
+	require.Equal(t, "HTTP/1.1", resp.Proto)
+
+	records, err := h2c.Verify(t.Context(), http.MethodGet, "status")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "HTTP/2.0", records[0].Protocol)
+	records, err = http1.Verify(t.Context(), http.MethodGet, "status")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "HTTP/1.1", records[0].Protocol)
+}
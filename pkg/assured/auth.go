@@ -0,0 +1,51 @@
+package assured
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// adminAuth is the credentials required by the assured admin endpoints, a bearer token and/or basic auth
+type adminAuth struct {
+	token    string
+	username string
+	password string
+}
+
+// authorized returns whether the request carries the bearer token or basic auth credentials
+func (a *adminAuth) authorized(r *http.Request) bool {
+	if a == nil {
+		return true
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && a.token != "" {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1
+	}
+	if username, password, ok := r.BasicAuth(); ok && a.username != "" {
+		return subtle.ConstantTimeCompare([]byte(username), []byte(a.username)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(password), []byte(a.password)) == 1
+	}
+	return false
+}
+
+// requireAdminAuth responds with 401 Unauthorized to requests without the admin credentials
+func requireAdminAuth(auth *adminAuth, fail errorWriter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !auth.authorized(r) {
+			if auth.username != "" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="assured"`)
+			} else {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="assured"`)
+			}
+			fail(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// basicAuth returns the value of a basic Authorization header
+func basicAuth(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
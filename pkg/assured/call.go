@@ -1,15 +1,22 @@
 package assured
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 // Call is a structure containing a request that is stubbed or made
 type Call struct {
+	ID         string            `json:"id,omitempty"`
 	Path       string            `json:"path"`
 	Method     string            `json:"method"`
 	StatusCode int               `json:"status_code,omitzero"`
@@ -18,6 +25,33 @@ type Call struct {
 	Query      map[string]string `json:"query,omitempty"`
 	Response   CallResponse      `json:"response,omitempty"`
 	Callbacks  []Callback        `json:"callbacks,omitempty"`
+
+	// Body, JSONBody, Base64Body and BodyFile explicitly set the response body, instead of Response.
+	// At most one body may be set.
+	Body       string          `json:"body,omitempty"`
+	JSONBody   json.RawMessage `json:"json_body,omitempty"`
+	Base64Body []byte          `json:"base64_body,omitempty"`
+	BodyFile   string          `json:"body_file,omitempty"`
+
+	// SequentialCallbacks sends the callbacks one after another in their declared order,
+	// rather than all at once.
+	SequentialCallbacks bool `json:"sequential_callbacks,omitempty"`
+
+	// Chunks streams the response body as a sequence of chunks, flushing each chunk after its delay,
+	// instead of writing a single body. ChunkFormat encodes each chunk as a Server-Sent Event or
+	// newline delimited JSON, or else writes the chunks as they are.
+	Chunks      []Chunk     `json:"chunks,omitempty"`
+	ChunkFormat ChunkFormat `json:"chunk_format,omitempty"`
+
+	// WebSocket upgrades the connection and plays a scripted conversation, instead of writing a response.
+	WebSocket *WebSocket `json:"websocket,omitempty"`
+
+	// GRPC sets the status and trailers of a stubbed gRPC call, whose method is GRPCMethod.
+	GRPC *GRPCStatus `json:"grpc,omitempty"`
+
+	// GraphQL matches GraphQL requests by operation, query and variables, and sets the data and errors
+	// of the response. Calls without GraphQL match every request with their method and path.
+	GraphQL *GraphQL `json:"graphql,omitempty"`
 }
 
 // Key is used as a matching string when selecting stubs
@@ -27,12 +61,136 @@ func (c Call) Key() string {
 
 // String converts a Call's Response into a string
 func (c Call) String() string {
-	rawString := string(c.Response)
+	body, _ := c.source().read()
+	rawString := string(body)
 
 	// TODO: implement string replacements for special cases
 	return rawString
 }
 
+// source returns the Call's response body source
+func (c Call) source() bodySource {
+	return bodySource{
+		response:   c.Response,
+		body:       c.Body,
+		jsonBody:   c.JSONBody,
+		base64Body: c.Base64Body,
+		file:       c.BodyFile,
+		graphQL:    c.GraphQL,
+	}
+}
+
+// bodySource is the set of fields a Call or Callback body may be read from
+type bodySource struct {
+	response   CallResponse
+	body       string
+	jsonBody   json.RawMessage
+	base64Body []byte
+	file       string
+	graphQL    *GraphQL
+}
+
+// validate checks that at most one body is set, and that a body file can be read
+func (s bodySource) validate() error {
+	set := 0
+	for _, ok := range []bool{len(s.response) > 0, s.body != "", len(s.jsonBody) > 0, len(s.base64Body) > 0, s.file != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set > 1 {
+		return errors.New("only one of response, body, json_body, base64_body or body_file may be set")
+	}
+	if set > 0 && s.graphQL.hasPayload() {
+		return errors.New("graphql data and errors cannot be set with a response body")
+	}
+	if s.file != "" {
+		if _, err := os.Stat(s.file); err != nil {
+			return fmt.Errorf("invalid body_file: %w", err)
+		}
+	}
+	return nil
+}
+
+// read returns the body from whichever source is set
+func (s bodySource) read() ([]byte, error) {
+	switch {
+	case s.body != "":
+		return []byte(s.body), nil
+	case len(s.jsonBody) > 0:
+		return s.jsonBody, nil
+	case len(s.base64Body) > 0:
+		return s.base64Body, nil
+	case s.file != "":
+		return os.ReadFile(s.file)
+	case s.graphQL.hasPayload():
+		return s.graphQL.payload()
+	}
+	return s.response, nil
+}
+
+// ChunkFormat is how the chunks of a streamed response are encoded
+type ChunkFormat string
+
+const (
+	// ChunkFormatRaw writes each chunk's body as it is
+	ChunkFormatRaw ChunkFormat = ""
+	// ChunkFormatSSE writes each chunk as a Server-Sent Event
+	ChunkFormatSSE ChunkFormat = "sse"
+	// ChunkFormatNDJSON writes each chunk followed by a newline
+	ChunkFormatNDJSON ChunkFormat = "ndjson"
+)
+
+// Chunk is a structure containing a part of a streamed response body
+type Chunk struct {
+	Body     string          `json:"body,omitempty"`
+	JSONBody json.RawMessage `json:"json_body,omitempty"`
+
+	// Event and ID set the event type and id of a Server-Sent Event
+	Event string `json:"event,omitempty"`
+	ID    string `json:"id,omitempty"`
+
+	// DelayMS is how many milliseconds to wait before writing the chunk
+	DelayMS int `json:"delay_ms,omitempty"`
+}
+
+// Encode returns the chunk as it is written in the given format
+func (c Chunk) Encode(format ChunkFormat) []byte {
+	body := []byte(c.Body)
+	if len(c.JSONBody) > 0 {
+		body = c.JSONBody
+	}
+	switch format {
+	case ChunkFormatSSE:
+		var b bytes.Buffer
+		if c.ID != "" {
+			fmt.Fprintf(&b, "id: %s\n", c.ID)
+		}
+		if c.Event != "" {
+			fmt.Fprintf(&b, "event: %s\n", c.Event)
+		}
+		for _, line := range strings.Split(string(body), "\n") {
+			fmt.Fprintf(&b, "data: %s\n", line)
+		}
+		b.WriteString("\n")
+		return b.Bytes()
+	case ChunkFormatNDJSON:
+		return append(bytes.TrimRight(body, "\n"), '\n')
+	}
+	return body
+}
+
+// validate checks that a chunk has at most one body
+func (c Chunk) validate() error {
+	if c.Body != "" && len(c.JSONBody) > 0 {
+		return errors.New("only one of chunk body or json_body may be set")
+	}
+	if c.DelayMS < 0 {
+		return errors.New("chunk delay_ms cannot be negative")
+	}
+	return nil
+}
+
 // CallResponse allows control over the Call's Response encoding
 type CallResponse []byte
 
@@ -67,11 +225,68 @@ func (response *CallResponse) UnmarshalJSON(data []byte) error {
 
 // Callback is a structure containing a callback that is stubbed
 type Callback struct {
+	// ID is assigned by the server to a triggered callback, to stop it before it is sent or while it repeats
+	ID string `json:"id,omitempty"`
+
 	Target   string            `json:"target"`
 	Method   string            `json:"method"`
 	Delay    int               `json:"delay,omitempty"`
+	Interval int               `json:"interval,omitempty"`
 	Headers  map[string]string `json:"headers"`
 	Response CallResponse      `json:"response,omitempty"`
+
+	// RequirePrevious stops a sequence of callbacks at this callback if the previous
+	// callback failed or did not respond with a 2xx status code.
+	RequirePrevious bool `json:"require_previous,omitempty"`
+
+	// Body, JSONBody, Base64Body and BodyFile explicitly set the callback body, instead of Response.
+	// At most one body may be set.
+	Body       string          `json:"body,omitempty"`
+	JSONBody   json.RawMessage `json:"json_body,omitempty"`
+	Base64Body []byte          `json:"base64_body,omitempty"`
+	BodyFile   string          `json:"body_file,omitempty"`
+
+	// TLS configures the client used to send the callback to an HTTPS target.
+	TLS *CallbackTLS `json:"tls,omitempty"`
+
+	// client sends the callback with its TLS settings, built once when the callback is validated
+	client *http.Client
+}
+
+// source returns the Callback's body source
+func (c Callback) source() bodySource {
+	return bodySource{response: c.Response, body: c.Body, jsonBody: c.JSONBody, base64Body: c.Base64Body, file: c.BodyFile}
+}
+
+// CallbackTLS is a structure containing the TLS settings used to send a callback
+type CallbackTLS struct {
+	CAFile             string `json:"ca_file,omitempty"`
+	CertFile           string `json:"cert_file,omitempty"`
+	KeyFile            string `json:"key_file,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// Config builds the tls configuration for the callback TLS settings
+func (t CallbackTLS) Config() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: t.InsecureSkipVerify}
+	if t.CAFile != "" {
+		ca, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read callback ca file: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in callback ca file %s", t.CAFile)
+		}
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load callback client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
 }
 
 // Record is a structure containing a the stored call that was made against the assured server
@@ -81,8 +296,29 @@ type Record struct {
 	Headers map[string]string `json:"headers,omitempty"`
 	Query   map[string]string `json:"query,omitempty"`
 	Body    []byte            `json:"body,omitempty"`
+
+	// Protocol is the http protocol the request was made with, such as HTTP/1.1 or HTTP/2.0
+	Protocol string `json:"protocol,omitempty"`
+
+	// ClientCert is the subject of the client certificate the request was made with, if any
+	ClientCert string `json:"client_cert,omitempty"`
+
+	// Violations lists the ways the request did not conform to the server's OpenAPI document, if validated
+	Violations []string `json:"violations,omitempty"`
 }
 
 func (r Record) Key() string {
 	return fmt.Sprintf("%s:%s", r.Method, r.Path)
 }
+
+// StubSwap is a structure containing the IDs of stubbed calls to delete and the calls to stub in their place
+type StubSwap struct {
+	Delete []string `json:"delete"`
+	Calls  []Call   `json:"calls"`
+}
+
+// Snapshot is a structure containing the stubbed calls and records of an assured server
+type Snapshot struct {
+	Calls   []Call   `json:"calls"`
+	Records []Record `json:"records"`
+}
@@ -1,6 +1,8 @@
 package assured
 
 import (
+	"slices"
+	"sort"
 	"sync"
 )
 
@@ -35,6 +37,45 @@ func (c *Store[T]) Rotate(v T) {
 	c.Unlock()
 }
 
+// Next returns the first value stored at the key that matches, moving it to the back of the key's rotation
+func (c *Store[T]) Next(key string, match func(T) bool) (T, bool) {
+	c.Lock()
+	defer c.Unlock()
+	values := c.data[key]
+	i := slices.IndexFunc(values, match)
+	if i < 0 {
+		var v T
+		return v, false
+	}
+	v := values[i]
+	c.data[key] = append(slices.Delete(slices.Clone(values), i, i+1), v)
+	return v, true
+}
+
+// NextKey returns the first value that matches at the first key, in key order, that matches, moving it to the
+// back of its key's rotation
+func (c *Store[T]) NextKey(matchKey func(string) bool, match func(T) bool) (T, bool) {
+	c.Lock()
+	defer c.Unlock()
+	keys := make([]string, 0, len(c.data))
+	for key := range c.data {
+		if matchKey(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		values := c.data[key]
+		if i := slices.IndexFunc(values, match); i >= 0 {
+			v := values[i]
+			c.data[key] = append(slices.Delete(slices.Clone(values), i, i+1), v)
+			return v, true
+		}
+	}
+	var v T
+	return v, false
+}
+
 func (c *Store[T]) Get(key string) []T {
 	c.Lock()
 	calls := c.data[key]
@@ -42,12 +83,122 @@ func (c *Store[T]) Get(key string) []T {
 	return calls
 }
 
+// All returns every stored value, ordered by key and then by position within the key
+func (c *Store[T]) All() []T {
+	c.Lock()
+	defer c.Unlock()
+	keys := make([]string, 0, len(c.data))
+	for key := range c.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	all := []T{}
+	for _, key := range keys {
+		all = append(all, c.data[key]...)
+	}
+	return all
+}
+
+// Find returns the first stored value that matches
+func (c *Store[T]) Find(match func(T) bool) (T, bool) {
+	c.Lock()
+	defer c.Unlock()
+	for _, values := range c.data {
+		if i := slices.IndexFunc(values, match); i >= 0 {
+			return values[i], true
+		}
+	}
+	var v T
+	return v, false
+}
+
+// Replace swaps the first stored value that matches with v, keeping its position when the key is unchanged
+func (c *Store[T]) Replace(match func(T) bool, v T) bool {
+	c.Lock()
+	defer c.Unlock()
+	for key, values := range c.data {
+		i := slices.IndexFunc(values, match)
+		if i < 0 {
+			continue
+		}
+		if key == v.Key() {
+			c.data[key] = slices.Clone(values)
+			c.data[key][i] = v
+			return true
+		}
+		c.remove(key, i)
+		c.data[v.Key()] = append(c.data[v.Key()], v)
+		return true
+	}
+	return false
+}
+
+// Swap deletes every stored value that matches and adds the values, in order, returning the deleted values
+func (c *Store[T]) Swap(match func(T) bool, values []T) []T {
+	c.Lock()
+	defer c.Unlock()
+	removed := []T{}
+	for key, stored := range c.data {
+		if !slices.ContainsFunc(stored, match) {
+			continue
+		}
+		kept := []T{}
+		for _, v := range stored {
+			if match(v) {
+				removed = append(removed, v)
+			} else {
+				kept = append(kept, v)
+			}
+		}
+		c.data[key] = kept
+		if len(kept) == 0 {
+			delete(c.data, key)
+		}
+	}
+	for _, v := range values {
+		c.data[v.Key()] = append(c.data[v.Key()], v)
+	}
+	return removed
+}
+
+// Remove deletes the first stored value that matches
+func (c *Store[T]) Remove(match func(T) bool) bool {
+	c.Lock()
+	defer c.Unlock()
+	for key, values := range c.data {
+		if i := slices.IndexFunc(values, match); i >= 0 {
+			c.remove(key, i)
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Store[T]) remove(key string, i int) {
+	c.data[key] = slices.Delete(slices.Clone(c.data[key]), i, i+1)
+	if len(c.data[key]) == 0 {
+		delete(c.data, key)
+	}
+}
+
 func (c *Store[T]) Clear(key string) {
 	c.Lock()
 	delete(c.data, key)
 	c.Unlock()
 }
 
+// Reset replaces every stored value with the given values, in order
+func (c *Store[T]) Reset(values []T) {
+	data := map[string][]T{}
+	for _, v := range values {
+		data[v.Key()] = append(data[v.Key()], v)
+	}
+	c.Lock()
+	c.data = data
+	c.Unlock()
+}
+
 func (c *Store[T]) ClearAll() {
 	c.Lock()
 	c.data = map[string][]T{}
@@ -145,3 +145,93 @@ func TestCallUnmarshalCallbacks(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, expected, call)
 }
+
+func TestCallUnmarshalExplicitBodies(t *testing.T) {
+	raw := `{
+		"path": "test/assured",
+		"method": "GET",
+		"json_body": {"assured": true},
+		"callbacks": [
+			{"target": "http://faketarget.com/", "base64_body": "eyJkb25lIjogdHJ1ZX0="},
+			{"target": "http://faketarget.com/", "body": "testdata/assured.json"}
+		]
+	}`
+
+	call := Call{}
+	err := json.Unmarshal([]byte(raw), &call)
+	require.NoError(t, err)
+	require.Equal(t, Call{
+		Path:     "test/assured",
+		Method:   http.MethodGet,
+		JSONBody: json.RawMessage(`{"assured": true}`),
+		Callbacks: []Callback{
+			{Target: "http://faketarget.com/", Base64Body: []byte(`{"done": true}`)},
+			{Target: "http://faketarget.com/", Body: "testdata/assured.json"},
+		},
+	}, call)
+}
+
+func TestBodySource(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  bodySource
+		want    []byte
+		wantErr string
+	}{
+		{name: "empty", source: bodySource{}, want: nil},
+		{name: "response", source: bodySource{response: []byte("response")}, want: []byte("response")},
+		{name: "body is never a file path", source: bodySource{body: "testdata/assured.json"}, want: []byte("testdata/assured.json")},
+		{name: "json body", source: bodySource{jsonBody: json.RawMessage(`{"assured": true}`)}, want: []byte(`{"assured": true}`)},
+		{name: "base64 body", source: bodySource{base64Body: []byte{0xff, 0xd8}}, want: []byte{0xff, 0xd8}},
+		{name: "body file", source: bodySource{file: "testdata/assured.json"}, want: []byte(`{"assured": true}`)},
+		{
+			name:    "missing body file",
+			source:  bodySource{file: "testdata/missing.json"},
+			wantErr: "invalid body_file: stat testdata/missing.json: no such file or directory",
+		},
+		{
+			name:    "multiple bodies",
+			source:  bodySource{body: "body", file: "testdata/assured.json"},
+			wantErr: "only one of response, body, json_body, base64_body or body_file may be set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.source.validate()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			body, err := tt.source.read()
+			require.NoError(t, err)
+			require.Equal(t, tt.want, body)
+		})
+	}
+}
+
+func TestChunkEncode(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunk  Chunk
+		format ChunkFormat
+		want   string
+	}{
+		{name: "raw", chunk: Chunk{Body: "partial"}, format: ChunkFormatRaw, want: "partial"},
+		{name: "raw json", chunk: Chunk{JSONBody: json.RawMessage(`{"n":1}`)}, format: ChunkFormatRaw, want: `{"n":1}`},
+		{name: "ndjson", chunk: Chunk{JSONBody: json.RawMessage(`{"n":1}`)}, format: ChunkFormatNDJSON, want: "{\"n\":1}\n"},
+		{name: "ndjson trailing newline", chunk: Chunk{Body: "{\"n\":1}\n"}, format: ChunkFormatNDJSON, want: "{\"n\":1}\n"},
+		{name: "sse", chunk: Chunk{Body: "hello"}, format: ChunkFormatSSE, want: "data: hello\n\n"},
+		{
+			name:   "sse event",
+			chunk:  Chunk{ID: "1", Event: "delta", Body: "line one\nline two"},
+			format: ChunkFormatSSE,
+			want:   "id: 1\nevent: delta\ndata: line one\ndata: line two\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, string(tt.chunk.Encode(tt.format)))
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 )
 
@@ -24,103 +25,328 @@ func NewClient(opts ...ClientOption) *Client {
 	return c
 }
 
+// Service returns a client scoped to a named virtual service of the assured server
+func (c *Client) Service(name string) *Client {
+	scoped := &Client{ClientOptions: c.ClientOptions}
+	WithClientHeader(ServiceHeader, name)(&scoped.ClientOptions)
+	return scoped
+}
+
+// NewSession starts an isolated session and returns a client scoped to it. The session's calls are made
+// against SessionURL, or with the SessionHeader, and the scoped client's Given, Verify and ClearAll touch
+// only the session's stubbed calls and records.
+func (c *Client) NewSession(ctx context.Context) (*Client, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.assuredURL("v2/sessions"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	if err := c.process(req, &session); err != nil {
+		return nil, err
+	}
+	scoped := &Client{ClientOptions: c.ClientOptions}
+	WithClientHeader(SessionHeader, session.ID)(&scoped.ClientOptions)
+	return scoped, nil
+}
+
+// SessionURL returns the url to use to test the stubbed endpoints of the client's session
+func (c *Client) SessionURL() string {
+	if id := c.headers.Get(SessionHeader); id != "" {
+		return c.assuredURL("sessions/" + id)
+	}
+	return ""
+}
+
+// EndSession ends the client's session, discarding its stubbed calls and records
+func (c *Client) EndSession(ctx context.Context) error {
+	id := c.headers.Get(SessionHeader)
+	if id == "" {
+		return fmt.Errorf("client is not scoped to a session")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.assuredURL("v2/sessions/"+id), nil)
+	if err != nil {
+		return err
+	}
+	return c.process(req, nil)
+}
+
 // Given stubs assured Call(s)
 func (c *Client) Given(ctx context.Context, calls ...Call) error {
 	for _, call := range calls {
-		b, err := json.Marshal(call)
-		if err != nil {
+		if _, err := c.Stub(ctx, call); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		req, err := http.NewRequestWithContext(ctx, call.Method, c.assuredURL("assured/given"), bytes.NewReader(b))
-		if err != nil {
-			return err
-		}
+// Stub stubs an assured Call and returns it with its server assigned ID
+func (c *Client) Stub(ctx context.Context, call Call) (Call, error) {
+	b, err := json.Marshal(call)
+	if err != nil {
+		return Call{}, err
+	}
 
-		if err := c.process(req, nil); err != nil {
-			return err
-		}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.assuredURL("v2/stubs"), bytes.NewReader(b))
+	if err != nil {
+		return Call{}, err
 	}
-	return nil
+
+	var stub Call
+	if err := c.process(req, &stub); err != nil {
+		return Call{}, err
+	}
+	return stub, nil
 }
 
-// Verify returns all of the records made against a stubbed method and path
-func (c *Client) Verify(ctx context.Context, method, path string) ([]Record, error) {
-	body := Call{
-		Method: method,
-		Path:   path,
+// Stubs returns all of the stubbed assured Calls
+func (c *Client) Stubs(ctx context.Context) ([]Call, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.assuredURL("v2/stubs"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var calls []Call
+	if err := c.process(req, &calls); err != nil {
+		return nil, err
 	}
-	b, err := json.Marshal(body)
+	return calls, nil
+}
+
+// ReplaceStubs atomically replaces all of the stubbed assured Calls, keeping any Records
+func (c *Client) ReplaceStubs(ctx context.Context, calls ...Call) ([]Call, error) {
+	if calls == nil {
+		calls = []Call{}
+	}
+	b, err := json.Marshal(calls)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.assuredURL("assured/verify"), bytes.NewBuffer(b))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.assuredURL("v2/stubs"), bytes.NewReader(b))
 	if err != nil {
 		return nil, err
 	}
 
-	var records []Record
-	if err = c.process(req, &records); err != nil {
+	var stubs []Call
+	if err := c.process(req, &stubs); err != nil {
 		return nil, err
 	}
-	return records, nil
+	return stubs, nil
 }
 
-// Clear assured calls for a Method and Path
-func (c *Client) Clear(ctx context.Context, method, path string) error {
-	body := Call{
-		Method: method,
-		Path:   path,
+// SwapStubs atomically deletes the stubbed assured Calls with the given IDs and stubs the calls in their place,
+// returning them with their server assigned IDs. Nothing is changed if any call is rejected.
+func (c *Client) SwapStubs(ctx context.Context, ids []string, calls ...Call) ([]Call, error) {
+	b, err := json.Marshal(StubSwap{Delete: ids, Calls: calls})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, c.assuredURL("v2/stubs"), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	var stubs []Call
+	if err := c.process(req, &stubs); err != nil {
+		return nil, err
 	}
-	b, err := json.Marshal(body)
+	return stubs, nil
+}
+
+// GetStub returns the stubbed assured Call with the given ID
+func (c *Client) GetStub(ctx context.Context, id string) (Call, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.assuredURL("v2/stubs/"+url.PathEscape(id)), nil)
+	if err != nil {
+		return Call{}, err
+	}
+
+	var call Call
+	if err := c.process(req, &call); err != nil {
+		return Call{}, err
+	}
+	return call, nil
+}
+
+// UpdateStub replaces the stubbed assured Call with the given ID
+func (c *Client) UpdateStub(ctx context.Context, id string, call Call) (Call, error) {
+	b, err := json.Marshal(call)
+	if err != nil {
+		return Call{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.assuredURL("v2/stubs/"+url.PathEscape(id)), bytes.NewReader(b))
+	if err != nil {
+		return Call{}, err
+	}
+
+	var stub Call
+	if err := c.process(req, &stub); err != nil {
+		return Call{}, err
+	}
+	return stub, nil
+}
+
+// DeleteStub removes the stubbed assured Call with the given ID, leaving other Calls for its Method and Path
+func (c *Client) DeleteStub(ctx context.Context, id string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.assuredURL("v2/stubs/"+url.PathEscape(id)), nil)
+	if err != nil {
+		return err
+	}
+	return c.process(req, nil)
+}
+
+// Snapshot exports all of the stubbed assured Calls and Records
+func (c *Client) Snapshot(ctx context.Context) (Snapshot, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.assuredURL("v2/snapshot"), nil)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	var snapshot Snapshot
+	if err := c.process(req, &snapshot); err != nil {
+		return Snapshot{}, err
+	}
+	return snapshot, nil
+}
+
+// Restore replaces all of the stubbed assured Calls and Records with a Snapshot
+func (c *Client) Restore(ctx context.Context, snapshot Snapshot) error {
+	b, err := json.Marshal(snapshot)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.assuredURL("assured/clear"), bytes.NewReader(b))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.assuredURL("v2/snapshot"), bytes.NewReader(b))
 	if err != nil {
 		return err
 	}
 	return c.process(req, nil)
 }
 
-// ClearAll clears all assured calls
+// Trigger sends an assured Callback on demand, without requiring a stubbed call to be made
+func (c *Client) Trigger(ctx context.Context, callback Callback) error {
+	_, err := c.TriggerCallback(ctx, callback)
+	return err
+}
+
+// TriggerCallback sends an assured Callback on demand and returns it with its server assigned ID, which
+// CancelCallback stops it with
+func (c *Client) TriggerCallback(ctx context.Context, callback Callback) (Callback, error) {
+	b, err := json.Marshal(callback)
+	if err != nil {
+		return Callback{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.assuredURL("v2/callbacks"), bytes.NewReader(b))
+	if err != nil {
+		return Callback{}, err
+	}
+
+	var triggered Callback
+	if err := c.process(req, &triggered); err != nil {
+		return Callback{}, err
+	}
+	return triggered, nil
+}
+
+// CancelCallback stops a triggered assured Callback by its ID, before it is sent or while it repeats
+func (c *Client) CancelCallback(ctx context.Context, id string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.assuredURL("v2/callbacks/"+url.PathEscape(id)), nil)
+	if err != nil {
+		return err
+	}
+	return c.process(req, nil)
+}
+
+// Verify returns all of the records made against a stubbed method and path
+func (c *Client) Verify(ctx context.Context, method, path string) ([]Record, error) {
+	return c.requests(ctx, keyQuery(method, path))
+}
+
+// VerifyGraphQL returns all of the GraphQL records made against a stubbed method and path for an operation name
+func (c *Client) VerifyGraphQL(ctx context.Context, method, path, operationName string) ([]Record, error) {
+	query := keyQuery(method, path)
+	query.Set("operation_name", operationName)
+	return c.requests(ctx, query)
+}
+
+// requests returns the records filtered by the query
+func (c *Client) requests(ctx context.Context, query url.Values) ([]Record, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.assuredURL("v2/requests?"+query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	if err = c.process(req, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Clear assured calls and records for a Method and Path
+func (c *Client) Clear(ctx context.Context, method, path string) error {
+	return c.clear(ctx, "?"+keyQuery(method, path).Encode())
+}
+
+// ClearAll clears all assured calls and records
 func (c *Client) ClearAll(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.assuredURL("assured/clearall"), nil)
+	return c.clear(ctx, "")
+}
+
+// clear deletes the stubbed calls and records selected by the query, in one request
+func (c *Client) clear(ctx context.Context, query string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.assuredURL("v2/snapshot"+query), nil)
 	if err != nil {
 		return err
 	}
 	return c.process(req, nil)
 }
 
+// keyQuery returns the query parameters selecting a method and path
+func keyQuery(method, path string) url.Values {
+	return url.Values{"method": []string{method}, "path": []string{path}}
+}
+
+// assuredURL returns the url of an assured admin endpoint, under the admin prefix
 func (c *Client) assuredURL(path string) string {
 	base := strings.TrimRight(c.baseURL, "/")
-	return fmt.Sprintf("%s/%s", base, strings.TrimPrefix(path, "/"))
+	return fmt.Sprintf("%s%s/%s", base, c.adminPrefix, strings.TrimPrefix(path, "/"))
 }
 
 // process executes an HTTP request, applies shared error handling, and optionally unmarshals JSON into out.
 func (c *Client) process(req *http.Request, out any) error {
+	for key, values := range c.headers {
+		req.Header[key] = values
+	}
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		message := "unexpected response"
 		if len(bodyBytes) > 0 {
 			var apiError APIError
+			var problem Problem
 			if err = json.Unmarshal(bodyBytes, &apiError); err == nil && apiError.Error != "" {
 				message = apiError.Error
+			} else if err = json.Unmarshal(bodyBytes, &problem); err == nil && problem.Detail != "" {
+				message = strings.Join(append([]string{problem.Detail}, problem.Errors...), ": ")
+			} else if err == nil && problem.Title != "" {
+				message = strings.Join(append([]string{problem.Title}, problem.Errors...), ": ")
 			} else if trimmed := strings.TrimSpace(string(bodyBytes)); trimmed != "" {
 				message = trimmed
 			}
 		}
 		return fmt.Errorf("%d:%s", resp.StatusCode, message)
 	}
-	if out != nil {
+	if out != nil && resp.StatusCode != http.StatusNoContent {
 		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
 			return err
 		}
@@ -1,10 +1,14 @@
 package assured
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 var DefaultClientOptions = ClientOptions{
-	httpClient: http.DefaultClient,
-	baseURL:    "http://localhost",
+	httpClient:  http.DefaultClient,
+	baseURL:     "http://localhost",
+	adminPrefix: DefaultAdminPrefix,
 }
 
 // ClientOption configures the standalone client behavior.
@@ -12,8 +16,10 @@ type ClientOption func(*ClientOptions)
 
 // ClientOptions defines just the HTTP transport and base URL for the client.
 type ClientOptions struct {
-	httpClient *http.Client
-	baseURL    string
+	httpClient  *http.Client
+	baseURL     string
+	adminPrefix string
+	headers     http.Header
 }
 
 func (o *ClientOptions) applyOptions(opts ...ClientOption) {
@@ -28,6 +34,9 @@ func (o *ClientOptions) applyOptions(opts ...ClientOption) {
 	if o.baseURL == "" {
 		o.baseURL = "http://localhost"
 	}
+	if o.adminPrefix == "" {
+		o.adminPrefix = DefaultAdminPrefix
+	}
 }
 
 // WithClientHTTPClient sets the HTTP client used for requests.
@@ -45,3 +54,33 @@ func WithClientBaseURL(u string) ClientOption {
 		}
 	}
 }
+
+// WithClientAdminPrefix sets the path prefix of the assured admin endpoints the client calls.
+func WithClientAdminPrefix(prefix string) ClientOption {
+	return func(o *ClientOptions) {
+		if p := strings.Trim(prefix, "/"); p != "" {
+			o.adminPrefix = "/" + p
+		}
+	}
+}
+
+// WithClientToken authenticates every request to the assured admin endpoints with the bearer token.
+func WithClientToken(token string) ClientOption {
+	return WithClientHeader("Authorization", "Bearer "+token)
+}
+
+// WithClientBasicAuth authenticates every request to the assured admin endpoints with the basic auth credentials.
+func WithClientBasicAuth(username, password string) ClientOption {
+	return WithClientHeader("Authorization", basicAuth(username, password))
+}
+
+// WithClientHeader sets a header sent with every request to the assured server.
+func WithClientHeader(key, value string) ClientOption {
+	return func(o *ClientOptions) {
+		o.headers = o.headers.Clone()
+		if o.headers == nil {
+			o.headers = http.Header{}
+		}
+		o.headers.Set(key, value)
+	}
+}
@@ -16,8 +16,9 @@ func TestClientOptions_applyOptions(t *testing.T) {
 			name:    "default",
 			options: nil,
 			want: ClientOptions{
-				httpClient: http.DefaultClient,
-				baseURL:    "http://localhost",
+				httpClient:  http.DefaultClient,
+				baseURL:     "http://localhost",
+				adminPrefix: DefaultAdminPrefix,
 			},
 		},
 		{
@@ -26,8 +27,9 @@ func TestClientOptions_applyOptions(t *testing.T) {
 				WithClientHTTPClient(*http.DefaultClient),
 			},
 			want: ClientOptions{
-				httpClient: http.DefaultClient,
-				baseURL:    "http://localhost",
+				httpClient:  http.DefaultClient,
+				baseURL:     "http://localhost",
+				adminPrefix: DefaultAdminPrefix,
 			},
 		},
 		{
@@ -36,8 +38,56 @@ func TestClientOptions_applyOptions(t *testing.T) {
 				WithClientBaseURL("https://example.com"),
 			},
 			want: ClientOptions{
-				httpClient: http.DefaultClient,
-				baseURL:    "https://example.com",
+				httpClient:  http.DefaultClient,
+				baseURL:     "https://example.com",
+				adminPrefix: DefaultAdminPrefix,
+			},
+		},
+		{
+			name: "with header",
+			options: []ClientOption{
+				WithClientHeader(ServiceHeader, "payments"),
+			},
+			want: ClientOptions{
+				httpClient:  http.DefaultClient,
+				baseURL:     "http://localhost",
+				adminPrefix: DefaultAdminPrefix,
+				headers:     http.Header{ServiceHeader: []string{"payments"}},
+			},
+		},
+		{
+			name: "with admin prefix",
+			options: []ClientOption{
+				WithClientAdminPrefix("_admin"),
+			},
+			want: ClientOptions{
+				httpClient:  http.DefaultClient,
+				baseURL:     "http://localhost",
+				adminPrefix: "/_admin",
+			},
+		},
+		{
+			name: "with token",
+			options: []ClientOption{
+				WithClientToken("secret"),
+			},
+			want: ClientOptions{
+				httpClient:  http.DefaultClient,
+				baseURL:     "http://localhost",
+				adminPrefix: DefaultAdminPrefix,
+				headers:     http.Header{"Authorization": []string{"Bearer secret"}},
+			},
+		},
+		{
+			name: "with basic auth",
+			options: []ClientOption{
+				WithClientBasicAuth("admin", "secret"),
+			},
+			want: ClientOptions{
+				httpClient:  http.DefaultClient,
+				baseURL:     "http://localhost",
+				adminPrefix: DefaultAdminPrefix,
+				headers:     http.Header{"Authorization": []string{"Basic YWRtaW46c2VjcmV0"}},
 			},
 		},
 		{
@@ -47,8 +97,9 @@ func TestClientOptions_applyOptions(t *testing.T) {
 				WithClientBaseURL("http://localhost:1234"),
 			},
 			want: ClientOptions{
-				httpClient: http.DefaultClient,
-				baseURL:    "http://localhost:1234",
+				httpClient:  http.DefaultClient,
+				baseURL:     "http://localhost:1234",
+				adminPrefix: DefaultAdminPrefix,
 			},
 		},
 	}
@@ -0,0 +1,135 @@
+package assured
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// GraphQL is a structure containing how a stubbed call matches GraphQL requests, and the GraphQL response
+type GraphQL struct {
+	// OperationName, Query and Variables match GraphQL requests. Unset fields match every request.
+	// Queries match ignoring insignificant whitespace, commas and comments, and every stubbed variable
+	// must equal the request's variable.
+	OperationName string                     `json:"operation_name,omitempty"`
+	Query         string                     `json:"query,omitempty"`
+	Variables     map[string]json.RawMessage `json:"variables,omitempty"`
+
+	// Data and Errors set the GraphQL response payload, instead of a response body.
+	Data   json.RawMessage `json:"data,omitempty"`
+	Errors json.RawMessage `json:"errors,omitempty"`
+}
+
+// graphQLRequest is the structure of a GraphQL request, sent as a JSON body or as query parameters
+type graphQLRequest struct {
+	Query         string                     `json:"query"`
+	OperationName string                     `json:"operationName"`
+	Variables     map[string]json.RawMessage `json:"variables"`
+}
+
+// graphQLOperation matches the name of the first operation in a GraphQL document
+var graphQLOperation = regexp.MustCompile(`\b(?:query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`)
+
+// parseGraphQLRequest reads the GraphQL request from a record's JSON body, or else its query parameters.
+// The operation name defaults to the name of the first operation in the query.
+func parseGraphQLRequest(record Record) graphQLRequest {
+	var req graphQLRequest
+	if err := json.Unmarshal(record.Body, &req); err != nil || req.Query == "" {
+		req = graphQLRequest{Query: record.Query["query"], OperationName: record.Query["operationName"]}
+		_ = json.Unmarshal([]byte(record.Query["variables"]), &req.Variables)
+	}
+	if req.OperationName == "" {
+		if match := graphQLOperation.FindStringSubmatch(normalizeGraphQL(req.Query)); match != nil {
+			req.OperationName = match[1]
+		}
+	}
+	return req
+}
+
+// matches returns true if the record is a GraphQL request the stub matches. A nil GraphQL matches every record.
+func (g *GraphQL) matches(record Record) bool {
+	if !g.hasMatcher() {
+		return true
+	}
+	req := parseGraphQLRequest(record)
+	if g.OperationName != "" && g.OperationName != req.OperationName {
+		return false
+	}
+	if g.Query != "" && normalizeGraphQL(g.Query) != normalizeGraphQL(req.Query) {
+		return false
+	}
+	for name, want := range g.Variables {
+		got, ok := req.Variables[name]
+		if !ok {
+			return false
+		}
+		var a, b any
+		if json.Unmarshal(want, &a) != nil || json.Unmarshal(got, &b) != nil || !equalJSON(a, b) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasMatcher returns true if the GraphQL matches requests by operation name, query or variables
+func (g *GraphQL) hasMatcher() bool {
+	return g != nil && (g.OperationName != "" || g.Query != "" || len(g.Variables) > 0)
+}
+
+// hasPayload returns true if the GraphQL response data or errors are set
+func (g *GraphQL) hasPayload() bool {
+	return g != nil && (len(g.Data) > 0 || len(g.Errors) > 0)
+}
+
+// payload returns the GraphQL response body
+func (g *GraphQL) payload() ([]byte, error) {
+	return json.Marshal(struct {
+		Data   json.RawMessage `json:"data,omitempty"`
+		Errors json.RawMessage `json:"errors,omitempty"`
+	}{g.Data, g.Errors})
+}
+
+// normalizeGraphQL returns a GraphQL document without insignificant whitespace, commas and comments
+func normalizeGraphQL(query string) string {
+	var b strings.Builder
+	space, inString, escaped, comment := false, false, false, false
+	write := func(r rune) {
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	for _, r := range query {
+		switch {
+		case comment:
+			comment = r != '\n' && r != '\r'
+			space = true
+		case inString:
+			b.WriteRune(r)
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == '"':
+				inString = false
+			}
+		case r == '"':
+			write(r)
+			inString = true
+		case r == '#':
+			comment = true
+		case r == ',' || unicode.IsSpace(r):
+			space = true
+		case strings.ContainsRune("{}()[]:=!@$|&", r):
+			space = true
+			write(r)
+			space = true
+		default:
+			write(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package assured
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeGraphQL(t *testing.T) {
+	require.Equal(t,
+		normalizeGraphQL(`query GetPet($id: ID!) { pet(id: $id) { id name } }`),
+		normalizeGraphQL("# fetch a pet\nquery GetPet(\n  $id:ID!\n) {\n  pet(id:$id) {\n    id,\n    name\n  }\n}\n"),
+	)
+	require.NotEqual(t, normalizeGraphQL(`{ pet(name: "a b") }`), normalizeGraphQL(`{ pet(name: "a  b") }`))
+}
+
+func TestGraphQLMatches(t *testing.T) {
+	record := Record{Body: []byte(`{"query": "query GetPet($id: ID!) { pet(id: $id) { name } }", "variables": {"id": "7", "locale": "en"}}`)}
+	tests := []struct {
+		name    string
+		graphQL *GraphQL
+		record  Record
+		want    bool
+	}{
+		{name: "nil matches everything", graphQL: nil, record: Record{}, want: true},
+		{name: "payload only matches everything", graphQL: &GraphQL{Data: json.RawMessage(`{}`)}, record: Record{}, want: true},
+		{name: "operation name from query", graphQL: &GraphQL{OperationName: "GetPet"}, record: record, want: true},
+		{name: "other operation name", graphQL: &GraphQL{OperationName: "ListPets"}, record: record, want: false},
+		{name: "query", graphQL: &GraphQL{Query: "query GetPet($id:ID!){pet(id:$id){name}}"}, record: record, want: true},
+		{name: "other query", graphQL: &GraphQL{Query: "query GetPet($id:ID!){pet(id:$id){id}}"}, record: record, want: false},
+		{name: "variables subset", graphQL: &GraphQL{Variables: map[string]json.RawMessage{"id": json.RawMessage(`"7"`)}}, record: record, want: true},
+		{name: "other variables", graphQL: &GraphQL{Variables: map[string]json.RawMessage{"id": json.RawMessage(`"8"`)}}, record: record, want: false},
+		{name: "missing variables", graphQL: &GraphQL{Variables: map[string]json.RawMessage{"owner": json.RawMessage(`"me"`)}}, record: record, want: false},
+		{
+			name:    "query parameters",
+			graphQL: &GraphQL{OperationName: "GetPet", Variables: map[string]json.RawMessage{"id": json.RawMessage(`7`)}},
+			record:  Record{Query: map[string]string{"query": "query GetPet { pet }", "variables": `{"id": 7}`}},
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.graphQL.matches(tt.record))
+		})
+	}
+}
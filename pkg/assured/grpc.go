@@ -0,0 +1,234 @@
+package assured
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// GRPCMethod is the Call and Record method of gRPC calls, whose path is the fully-qualified
+// method name, such as grpc.health.v1.Health/Check
+const GRPCMethod = "GRPC"
+
+// GRPCStatus is a structure containing the status and trailers of a stubbed gRPC response
+type GRPCStatus struct {
+	// Code and Message set the gRPC status. A non-zero code responds with an error instead of the response body.
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	// Trailers are sent as trailer metadata, while the Call's headers are sent as header metadata.
+	Trailers map[string]string `json:"trailers,omitempty"`
+}
+
+// LoadDescriptorSet reads the services and messages of a protobuf descriptor set, such as one built with
+// `protoc --include_imports --descriptor_set_out` or `buf build -o`
+func LoadDescriptorSet(path string) (*protoregistry.Files, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read descriptor set: %w", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("parse descriptor set: %w", err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("parse descriptor set: %w", err)
+	}
+	return files, nil
+}
+
+// validateGRPCStatus checks that a stubbed gRPC status code is known
+func validateGRPCStatus(s GRPCStatus) error {
+	if s.Code < int(codes.OK) || s.Code > int(codes.Unauthenticated) {
+		return fmt.Errorf("invalid grpc status code %d", s.Code)
+	}
+	return nil
+}
+
+// newGRPCServer creates a gRPC server that responds to every method in the descriptor files with
+// stubbed calls, and serves reflection of the descriptor files
+func newGRPCServer(
+	logger *slog.Logger,
+	httpClient *http.Client,
+	calls *Store[Call],
+	records *Store[Record],
+	callbacks *callbackScopes,
+	trackRecords bool,
+	files *protoregistry.Files,
+	opts ...grpc.ServerOption,
+) *grpc.Server {
+	server := grpc.NewServer(append(opts,
+		grpc.UnknownServiceHandler(handleGRPC(logger, httpClient, calls, records, callbacks, trackRecords, files)))...)
+
+	reflectionOpts := reflection.ServerOptions{
+		Services:           descriptorServices{files},
+		DescriptorResolver: files,
+	}
+	reflectionv1.RegisterServerReflectionServer(server, reflection.NewServerV1(reflectionOpts))
+	reflectionv1alpha.RegisterServerReflectionServer(server, reflection.NewServer(reflectionOpts))
+	return server
+}
+
+// descriptorServices lists the services of descriptor files for server reflection
+type descriptorServices struct {
+	files *protoregistry.Files
+}
+
+// GetServiceInfo returns the services and their methods in the descriptor files
+func (d descriptorServices) GetServiceInfo() map[string]grpc.ServiceInfo {
+	services := map[string]grpc.ServiceInfo{}
+	d.files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+		for i := 0; i < file.Services().Len(); i++ {
+			service := file.Services().Get(i)
+			info := grpc.ServiceInfo{Metadata: file.Path()}
+			for j := 0; j < service.Methods().Len(); j++ {
+				method := service.Methods().Get(j)
+				info.Methods = append(info.Methods, grpc.MethodInfo{
+					Name:           string(method.Name()),
+					IsClientStream: method.IsStreamingClient(),
+					IsServerStream: method.IsStreamingServer(),
+				})
+			}
+			services[string(service.FullName())] = info
+		}
+		return true
+	})
+	return services
+}
+
+// handleGRPC is used to respond to a given assured gRPC call. Every request message is received and recorded
+// before responding. Server streaming methods send each of the call's chunks as a message.
+func handleGRPC(
+	logger *slog.Logger,
+	httpClient *http.Client,
+	calls *Store[Call],
+	records *Store[Record],
+	callbacks *callbackScopes,
+	trackRecords bool,
+	files *protoregistry.Files,
+) grpc.StreamHandler {
+	types := dynamicpb.NewTypes(files)
+	marshal := protojson.MarshalOptions{Resolver: types}
+	unmarshal := protojson.UnmarshalOptions{Resolver: types}
+
+	return func(_ any, stream grpc.ServerStream) error {
+		fullMethod, _ := grpc.MethodFromServerStream(stream)
+		path := strings.Trim(fullMethod, "/")
+		descriptor, err := files.FindDescriptorByName(protoreflect.FullName(strings.Replace(path, "/", ".", 1)))
+		if err != nil {
+			return status.Errorf(codes.Unimplemented, "unknown method %s", fullMethod)
+		}
+		method, ok := descriptor.(protoreflect.MethodDescriptor)
+		if !ok {
+			return status.Errorf(codes.Unimplemented, "unknown method %s", fullMethod)
+		}
+
+		headers := map[string]string{}
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		for key, values := range md {
+			headers[key] = strings.Join(values, ",")
+		}
+		clientCert := ""
+		if p, ok := peer.FromContext(stream.Context()); ok {
+			if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) > 0 {
+				clientCert = info.State.PeerCertificates[0].Subject.String()
+			}
+		}
+		received := []Record{}
+		for {
+			msg := dynamicpb.NewMessage(method.Input())
+			if err := stream.RecvMsg(msg); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return err
+			}
+			body, err := marshal.Marshal(msg)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid request: %s", err)
+			}
+			received = append(received, Record{Path: path, Method: GRPCMethod, Headers: headers, Body: body, ClientCert: clientCert})
+			if !method.IsStreamingClient() {
+				break
+			}
+		}
+
+		key := fmt.Sprintf("%s:%s", GRPCMethod, path)
+		assured, ok := calls.Next(key, func(Call) bool { return true })
+		if !ok {
+			logger.InfoContext(stream.Context(), "assured call not found", "key", key)
+			return status.Error(codes.Unimplemented, "no assured calls")
+		}
+
+		if trackRecords {
+			for _, record := range received {
+				records.Add(record)
+			}
+		}
+		// Trigger callbacks, if applicable
+		dispatchCallbacks(callbacks.scope(assured.ID), logger, httpClient, assured)
+
+		// Delay response
+		time.Sleep(time.Duration(assured.Delay) * time.Second)
+
+		if len(assured.Headers) > 0 {
+			if err := stream.SetHeader(metadata.New(assured.Headers)); err != nil {
+				return err
+			}
+		}
+		if assured.GRPC != nil {
+			stream.SetTrailer(metadata.New(assured.GRPC.Trailers))
+			if assured.GRPC.Code != int(codes.OK) {
+				logger.InfoContext(stream.Context(), "assured call responded", "key", key, "code", assured.GRPC.Code)
+				return status.Error(codes.Code(assured.GRPC.Code), assured.GRPC.Message)
+			}
+		}
+
+		chunks := assured.Chunks
+		if !method.IsStreamingServer() || len(chunks) == 0 {
+			body, err := assured.source().read()
+			if err != nil {
+				return status.Errorf(codes.Internal, "invalid assured response: %s", err)
+			}
+			chunks = []Chunk{{Body: string(body)}}
+		}
+		for _, chunk := range chunks {
+			if err := sleep(stream.Context(), chunk.DelayMS); err != nil {
+				return err
+			}
+			msg := dynamicpb.NewMessage(method.Output())
+			if body := chunk.Encode(ChunkFormatRaw); len(body) > 0 {
+				if err := unmarshal.Unmarshal(body, msg); err != nil {
+					return status.Errorf(codes.Internal, "invalid assured response: %s", err)
+				}
+			}
+			if err := stream.SendMsg(msg); err != nil {
+				return err
+			}
+		}
+		logger.InfoContext(stream.Context(), "assured call responded", "key", key)
+		return nil
+	}
+}
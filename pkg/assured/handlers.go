@@ -3,9 +3,16 @@ package assured
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -13,58 +20,210 @@ type APIError struct {
 	Error string `json:"error"`
 }
 
+// Problem is a structure containing an RFC 9457 problem details document
+type Problem struct {
+	Type   string   `json:"type,omitempty"`
+	Title  string   `json:"title"`
+	Status int      `json:"status"`
+	Detail string   `json:"detail,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// errorWriter writes an error response, as an APIError for the v1 admin API or a problem for the v2 admin API
+type errorWriter func(w http.ResponseWriter, status int, detail string)
+
+// apiError writes an APIError response with the detail
+func apiError(w http.ResponseWriter, status int, detail string) {
+	_ = encode(w, status, APIError{detail})
+}
+
+// methodNotAllowed responds to requests for a resource with a method it does not allow
+func methodNotAllowed(fail errorWriter, allow ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		fail(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %s is not allowed", r.Method))
+	}
+}
+
 func handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// handleGiven is used to stub out a call for a given path
-func handleGiven(logger *slog.Logger, calls *Store[Call]) http.HandlerFunc {
+// handleGiven is used to stub out a call for a given path, responding with the call and the status. The call's
+// location is set when the status is 201 Created.
+func handleGiven(
+	logger *slog.Logger,
+	httpClient *http.Client,
+	calls *Store[Call],
+	stubSpec *OpenAPI,
+	fail errorWriter,
+	status int,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		call, err := decode[Call](r)
 		if err != nil {
-			_ = encode(w, http.StatusBadRequest, APIError{err.Error()})
+			fail(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := validateCall(&call, httpClient); err != nil {
+			fail(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		// Sanitize Path
-		call.Path = strings.Trim(call.Path, "/")
+		if !conformsToContract(w, r, logger, stubSpec, call) {
+			return
+		}
+
+		call.ID = rand.Text()
+		calls.Add(call)
+		logger.InfoContext(r.Context(), "assured call set", "key", call.Key(), "id", call.ID)
 
-		if call.Method == "" {
-			call.Method = http.MethodGet
+		if status == http.StatusCreated {
+			w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+url.PathEscape(call.ID))
 		}
+		_ = encode(w, status, call)
+	}
+}
 
-		// validate http request
-		_, err = http.NewRequest(call.Method, call.Path, nil)
+// handleStubs returns all of the stubbed calls
+func handleStubs(calls *Store[Call]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_ = encode(w, http.StatusOK, calls.All())
+	}
+}
+
+// handleReplaceStubs atomically replaces all of the stubbed calls, keeping any records
+func handleReplaceStubs(
+	logger *slog.Logger,
+	httpClient *http.Client,
+	calls *Store[Call],
+	callbacks *callbackScopes,
+	stubSpec *OpenAPI,
+	fail errorWriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stubs, err := decode[[]Call](r)
 		if err != nil {
-			_ = encode(w, http.StatusBadRequest, APIError{err.Error()})
+			fail(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		for _, callback := range call.Callbacks {
-			if callback.Target == "" {
-				_ = encode(w, http.StatusBadRequest, APIError{"cannot stub callback without target"})
+		for i := range stubs {
+			if err := validateCall(&stubs[i], httpClient); err != nil {
+				fail(w, http.StatusBadRequest, err.Error())
 				return
 			}
-			_, err = http.NewRequest(callback.Method, callback.Target, nil)
-			if err != nil {
-				_ = encode(w, http.StatusBadRequest, APIError{err.Error()})
+			if !conformsToContract(w, r, logger, stubSpec, stubs[i]) {
 				return
 			}
+			stubs[i].ID = rand.Text()
 		}
 
-		calls.Add(call)
-		logger.InfoContext(r.Context(), "assured call set", "key", call.Key())
+		callbacks.cancelCalls(calls.All())
+		calls.Reset(stubs)
+		logger.InfoContext(r.Context(), "assured calls replaced", "calls", len(stubs))
+
+		_ = encode(w, http.StatusOK, stubs)
+	}
+}
+
+// handleGetStub returns a single stubbed call by its ID
+func handleGetStub(calls *Store[Call], fail errorWriter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		call, ok := calls.Find(matchID(r.PathValue("id")))
+		if !ok {
+			fail(w, http.StatusNotFound, "assured call not found")
+			return
+		}
+		_ = encode(w, http.StatusOK, call)
+	}
+}
+
+// handleUpdateStub replaces a single stubbed call by its ID
+func handleUpdateStub(
+	logger *slog.Logger,
+	httpClient *http.Client,
+	calls *Store[Call],
+	callbacks *callbackScopes,
+	stubSpec *OpenAPI,
+	fail errorWriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		call, err := decode[Call](r)
+		if err != nil {
+			fail(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := validateCall(&call, httpClient); err != nil {
+			fail(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if !conformsToContract(w, r, logger, stubSpec, call) {
+			return
+		}
+
+		call.ID = r.PathValue("id")
+		if !calls.Replace(matchID(call.ID), call) {
+			fail(w, http.StatusNotFound, "assured call not found")
+			return
+		}
+		callbacks.cancel(call.ID)
+		logger.InfoContext(r.Context(), "assured call updated", "key", call.Key(), "id", call.ID)
 
 		_ = encode(w, http.StatusOK, call)
 	}
 }
 
+// handleDeleteStub removes a single stubbed call by its ID, responding with the status
+func handleDeleteStub(logger *slog.Logger, calls *Store[Call], callbacks *callbackScopes, fail errorWriter, status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if !calls.Remove(matchID(id)) {
+			fail(w, http.StatusNotFound, "assured call not found")
+			return
+		}
+		callbacks.cancel(id)
+		logger.InfoContext(r.Context(), "assured call deleted", "id", id)
+		w.WriteHeader(status)
+	}
+}
+
 // handleWhen is used to respond to a given assured call
-func handleWhen(logger *slog.Logger, httpClient *http.Client, calls *Store[Call], records *Store[Record], trackRecords bool) http.HandlerFunc {
+func handleWhen(
+	ctx context.Context,
+	logger *slog.Logger,
+	httpClient *http.Client,
+	calls *Store[Call],
+	records *Store[Record],
+	callbacks *callbackScopes,
+	trackRecords bool,
+	validation *requestValidation,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		record := decodeAssuredRecord(r)
-		matched := calls.Get(record.Key())
-		if len(matched) == 0 {
+		if validation != nil {
+			record.Violations = validation.spec.ValidateRequest(record)
+			if len(record.Violations) > 0 {
+				logger.InfoContext(r.Context(), "assured call does not conform to openapi document", "key", record.Key(), "violations", record.Violations)
+			}
+			if len(record.Violations) > 0 && validation.mode == ValidationReject {
+				if trackRecords {
+					records.Add(record)
+				}
+				_ = encodeProblem(w, Problem{
+					Title:  "Request does not conform to the OpenAPI document",
+					Status: http.StatusBadRequest,
+					Errors: record.Violations,
+				})
+				return
+			}
+		}
+
+		assured, ok := nextCall(calls, record)
+		if !ok {
 			logger.InfoContext(r.Context(), "assured call not found", "key", record.Key())
 			_ = encode(w, http.StatusNotFound, APIError{"no assured calls"})
 			return
@@ -73,20 +232,128 @@ func handleWhen(logger *slog.Logger, httpClient *http.Client, calls *Store[Call]
 		if trackRecords {
 			records.Add(record)
 		}
-		assured := matched[0]
-		calls.Rotate(assured)
 
 		// Trigger callbacks, if applicable
-		for _, callback := range assured.Callbacks {
-			go sendCallback(r.Context(), logger, httpClient, callback)
-		}
+		dispatchCallbacks(callbacks.scope(assured.ID), logger, httpClient, assured)
 
 		// Delay response
 		time.Sleep(time.Duration(assured.Delay) * time.Second)
 
+		if assured.WebSocket != nil {
+			logger.InfoContext(r.Context(), "assured websocket connected", "key", record.Key())
+			serveWebSocket(ctx, logger, w, r, records, trackRecords, assured)
+			return
+		}
+
 		logger.InfoContext(r.Context(), "assured call responded", "key", record.Key())
-		_ = encodeAssuredCall(w, assured)
+		_ = encodeAssuredCall(w, r, assured)
+	}
+}
+
+// handleSnapshot exports all stubbed calls and records
+func handleSnapshot(calls *Store[Call], records *Store[Record]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_ = encode(w, http.StatusOK, Snapshot{
+			Calls:   calls.All(),
+			Records: records.All(),
+		})
+	}
+}
+
+// handleRestore replaces all stubbed calls and records with a snapshot, responding with the status
+func handleRestore(
+	logger *slog.Logger,
+	httpClient *http.Client,
+	calls *Store[Call],
+	records *Store[Record],
+	callbacks *callbackScopes,
+	stubSpec *OpenAPI,
+	fail errorWriter,
+	status int,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snapshot, err := decode[Snapshot](r)
+		if err != nil {
+			fail(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		for i := range snapshot.Calls {
+			if err := validateCall(&snapshot.Calls[i], httpClient); err != nil {
+				fail(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if !conformsToContract(w, r, logger, stubSpec, snapshot.Calls[i]) {
+				return
+			}
+			if snapshot.Calls[i].ID == "" {
+				snapshot.Calls[i].ID = rand.Text()
+			}
+		}
+
+		callbacks.cancelCalls(calls.All())
+		calls.Reset(snapshot.Calls)
+		records.Reset(snapshot.Records)
+		logger.InfoContext(r.Context(), "restored snapshot", "calls", len(snapshot.Calls), "records", len(snapshot.Records))
+		w.WriteHeader(status)
+	}
+}
+
+// handleTrigger is used to send a callback on demand, independent of any stubbed call, responding with the
+// callback, its server assigned ID, and the status. The callback's location is set when the status is 202 Accepted.
+func handleTrigger(logger *slog.Logger, httpClient *http.Client, callbacks *callbackScopes, fail errorWriter, status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		callback, err := decode[Callback](r)
+		if err != nil {
+			fail(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := validateCallback(&callback, httpClient); err != nil {
+			fail(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		callback.ID = rand.Text()
+		ctx := callbacks.trigger(callback.ID)
+		go func() {
+			defer callbacks.cancel(callback.ID)
+			triggerCallback(ctx, logger, httpClient, callback)
+		}()
+		logger.InfoContext(r.Context(), "assured callback triggered", "target", callback.Target, "interval", callback.Interval, "id", callback.ID)
+
+		if status == http.StatusAccepted {
+			w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+url.PathEscape(callback.ID))
+		}
+		_ = encode(w, status, callback)
+	}
+}
+
+// nextCall returns the stubbed call to respond to a record with, rotating it to the back of its key. Stubs of the
+// record's path take precedence over stubs of a path template that matches it, such as pets/{petId}, and stubs
+// matching a GraphQL request take precedence over stubs that match every request.
+func nextCall(calls *Store[Call], record Record) (Call, bool) {
+	graphQL := func(call Call) bool { return call.GraphQL.hasMatcher() && call.GraphQL.matches(record) }
+	generic := func(call Call) bool { return !call.GraphQL.hasMatcher() }
+	if call, ok := calls.Next(record.Key(), graphQL); ok {
+		return call, true
 	}
+	if call, ok := calls.Next(record.Key(), generic); ok {
+		return call, true
+	}
+
+	templated := func(key string) bool {
+		method, path, _ := strings.Cut(key, ":")
+		if method != record.Method || !strings.Contains(path, "{") {
+			return false
+		}
+		_, ok := matchPath(path, record.Path)
+		return ok
+	}
+	if call, ok := calls.NextKey(templated, graphQL); ok {
+		return call, true
+	}
+	return calls.NextKey(templated, generic)
 }
 
 // handleVerify returns all matching assured calls, used to verify a particular call
@@ -110,12 +377,17 @@ func handleVerify(records *Store[Record], trackRecords bool) http.HandlerFunc {
 		}
 
 		calls := records.Get(req.Key())
-		_ = encodeAssuredCall(w, calls)
+		if req.GraphQL != nil && req.GraphQL.OperationName != "" {
+			calls = slices.DeleteFunc(slices.Clone(calls), func(record Record) bool {
+				return parseGraphQLRequest(record).OperationName != req.GraphQL.OperationName
+			})
+		}
+		_ = encodeAssuredCall(w, r, calls)
 	}
 }
 
 // handleClear is used to clear a specific assured call
-func handleClear(logger *slog.Logger, calls *Store[Call], records *Store[Record]) http.HandlerFunc {
+func handleClear(logger *slog.Logger, calls *Store[Call], records *Store[Record], callbacks *callbackScopes) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		req, err := decode[Call](r)
 		if err != nil {
@@ -129,6 +401,7 @@ func handleClear(logger *slog.Logger, calls *Store[Call], records *Store[Record]
 			return
 		}
 
+		callbacks.cancelCalls(calls.Get(req.Key()))
 		calls.Clear(req.Key())
 		records.Clear(req.Key())
 		logger.InfoContext(r.Context(), "cleared calls for path", "key", req.Key())
@@ -136,30 +409,305 @@ func handleClear(logger *slog.Logger, calls *Store[Call], records *Store[Record]
 }
 
 // handleClearAll is used to clear all assured calls
-func handleClearAll(logger *slog.Logger, calls *Store[Call], records *Store[Record]) http.HandlerFunc {
+func handleClearAll(logger *slog.Logger, calls *Store[Call], records *Store[Record], callbacks *callbackScopes) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		callbacks.cancelCalls(calls.All())
 		calls.ClearAll()
 		records.ClearAll()
 		logger.InfoContext(r.Context(), "cleared all calls")
 	}
 }
 
-// sendCallback sends a given callback to its target
-func sendCallback(ctx context.Context, logger *slog.Logger, httpClient *http.Client, callback Callback) {
-	req, err := http.NewRequest(callback.Method, callback.Target, bytes.NewBuffer(callback.Response))
+// validateCall sanitizes a stubbed call and checks that it can be served, building the clients of its callbacks
+// from the http client
+func validateCall(call *Call, httpClient *http.Client) error {
+	// Sanitize Path
+	call.Path = strings.Trim(call.Path, "/")
+
+	if call.Method == "" {
+		call.Method = http.MethodGet
+	}
+
+	// validate http request
+	if _, err := http.NewRequest(call.Method, call.Path, nil); err != nil {
+		return err
+	}
+
+	if err := call.source().validate(); err != nil {
+		return err
+	}
+
+	if err := validateChunks(*call); err != nil {
+		return err
+	}
+
+	if call.WebSocket != nil {
+		if err := validateWebSocket(*call.WebSocket); err != nil {
+			return err
+		}
+	}
+
+	if call.GRPC != nil {
+		if err := validateGRPCStatus(*call.GRPC); err != nil {
+			return err
+		}
+	}
+
+	for i := range call.Callbacks {
+		if err := validateCallback(&call.Callbacks[i], httpClient); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateChunks checks that a streamed call has no other response body and a known chunk format
+func validateChunks(call Call) error {
+	if len(call.Chunks) == 0 {
+		return nil
+	}
+	if body, _ := call.source().read(); len(body) > 0 || call.BodyFile != "" {
+		return errors.New("chunks cannot be set with a response body")
+	}
+	switch call.ChunkFormat {
+	case ChunkFormatRaw, ChunkFormatSSE, ChunkFormatNDJSON:
+	default:
+		return fmt.Errorf("unknown chunk_format %q", call.ChunkFormat)
+	}
+	for _, chunk := range call.Chunks {
+		if err := chunk.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// conformsToContract checks a stubbed call against an OpenAPI document, if set, writing a problem document
+// to the response when the call's response could never be returned by the real service
+func conformsToContract(w http.ResponseWriter, r *http.Request, logger *slog.Logger, spec *OpenAPI, call Call) bool {
+	if spec == nil {
+		return true
+	}
+	violations := spec.ValidateCall(call)
+	if len(violations) == 0 {
+		return true
+	}
+	logger.InfoContext(r.Context(), "assured call does not conform to openapi document", "key", call.Key(), "violations", violations)
+	_ = encodeProblem(w, Problem{
+		Title:  "Call does not conform to the OpenAPI document",
+		Status: http.StatusBadRequest,
+		Errors: violations,
+	})
+	return false
+}
+
+// matchID selects the stubbed call with the given ID
+func matchID(id string) func(Call) bool {
+	return func(c Call) bool {
+		return c.ID == id
+	}
+}
+
+// validateCallback checks that a callback can be built into a request, and builds the client that sends a
+// callback with TLS settings from the http client
+func validateCallback(callback *Callback, httpClient *http.Client) error {
+	if callback.Target == "" {
+		return errors.New("cannot stub callback without target")
+	}
+	if _, err := http.NewRequest(callback.Method, callback.Target, nil); err != nil {
+		return err
+	}
+	if err := callback.source().validate(); err != nil {
+		return err
+	}
+	if callback.TLS != nil {
+		config, err := callback.TLS.Config()
+		if err != nil {
+			return err
+		}
+		callback.client = callbackClient(httpClient, config)
+	}
+	return nil
+}
+
+// callbackScopes are the contexts that the callbacks of stubbed calls, and triggered callbacks, are sent with,
+// by the ID of the stubbed call or triggered callback. A scope is cancelled when its stubbed call is cleared,
+// replaced or deleted, or its triggered callback is deleted, and every scope is cancelled with the server.
+type callbackScopes struct {
+	ctx    context.Context
+	scopes map[string]callbackScope
+	sync.Mutex
+}
+
+// callbackScope is the context of a stubbed call's callbacks, or of a triggered callback
+type callbackScope struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	triggered bool
+}
+
+func newCallbackScopes(ctx context.Context) *callbackScopes {
+	return &callbackScopes{ctx: ctx, scopes: map[string]callbackScope{}}
+}
+
+// scope returns the context of the ID's callbacks
+func (c *callbackScopes) scope(id string) context.Context {
+	c.Lock()
+	defer c.Unlock()
+	if scope, ok := c.scopes[id]; ok {
+		return scope.ctx
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.scopes[id] = callbackScope{ctx: ctx, cancel: cancel}
+	return ctx
+}
+
+// trigger returns the context of a triggered callback
+func (c *callbackScopes) trigger(id string) context.Context {
+	c.Lock()
+	defer c.Unlock()
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.scopes[id] = callbackScope{ctx: ctx, cancel: cancel, triggered: true}
+	return ctx
+}
+
+// cancelTriggered stops a triggered callback, returning whether it was being sent. The callbacks of stubbed
+// calls are not stopped.
+func (c *callbackScopes) cancelTriggered(id string) bool {
+	c.Lock()
+	defer c.Unlock()
+	scope, ok := c.scopes[id]
+	if !ok || !scope.triggered {
+		return false
+	}
+	scope.cancel()
+	delete(c.scopes, id)
+	return true
+}
+
+// cancel stops the callbacks of the IDs, returning whether any were being sent
+func (c *callbackScopes) cancel(ids ...string) bool {
+	c.Lock()
+	defer c.Unlock()
+	cancelled := false
+	for _, id := range ids {
+		if scope, ok := c.scopes[id]; ok {
+			scope.cancel()
+			delete(c.scopes, id)
+			cancelled = true
+		}
+	}
+	return cancelled
+}
+
+// cancelCalls stops the callbacks of the stubbed calls
+func (c *callbackScopes) cancelCalls(calls []Call) {
+	ids := make([]string, 0, len(calls))
+	for _, call := range calls {
+		ids = append(ids, call.ID)
+	}
+	c.cancel(ids...)
+}
+
+// cancelAll stops every callback
+func (c *callbackScopes) cancelAll() {
+	c.Lock()
+	defer c.Unlock()
+	for id, scope := range c.scopes {
+		scope.cancel()
+		delete(c.scopes, id)
+	}
+}
+
+// triggerCallback sends a triggered callback, repeating it every interval until the context is done
+func triggerCallback(ctx context.Context, logger *slog.Logger, httpClient *http.Client, callback Callback) {
+	_ = sendCallback(ctx, logger, httpClient, callback)
+	if callback.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(callback.Interval) * time.Second)
+	defer ticker.Stop()
+	callback.Delay = 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = sendCallback(ctx, logger, httpClient, callback)
+		}
+	}
+}
+
+// dispatchCallbacks sends a call's callbacks, either concurrently or sequentially in their declared order
+func dispatchCallbacks(ctx context.Context, logger *slog.Logger, httpClient *http.Client, call Call) {
+	if !call.SequentialCallbacks {
+		for _, callback := range call.Callbacks {
+			go func() { _ = sendCallback(ctx, logger, httpClient, callback) }()
+		}
+		return
+	}
+
+	go func() {
+		var err error
+		for i, callback := range call.Callbacks {
+			if err != nil && callback.RequirePrevious {
+				logger.InfoContext(ctx, "stopped callback sequence after failed callback", "key", call.Key(), "step", i, "error", err)
+				return
+			}
+			err = sendCallback(ctx, logger, httpClient, callback)
+		}
+	}()
+}
+
+// sendCallback sends a given callback to its target, returning an error if the target did not respond successfully
+func sendCallback(ctx context.Context, logger *slog.Logger, httpClient *http.Client, callback Callback) error {
+	body, err := callback.source().read()
+	if err != nil {
+		logger.InfoContext(ctx, "failed to read callback body", "target", callback.Target, "error", err)
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, callback.Method, callback.Target, bytes.NewReader(body))
 	if err != nil {
 		logger.InfoContext(ctx, "failed to build callback request", "target", callback.Target, "error", err)
-		return
+		return err
 	}
 	for key, value := range callback.Headers {
 		req.Header.Set(key, value)
 	}
+	if callback.client != nil {
+		httpClient = callback.client
+	}
+
 	// Delay callback, if applicable
-	time.Sleep(time.Duration(callback.Delay) * time.Second)
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-time.After(time.Duration(callback.Delay) * time.Second):
+	}
+
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		logger.InfoContext(ctx, "failed to reach callback target", "target", callback.Target, "error", err)
-		return
+		return err
 	}
+	_ = resp.Body.Close()
 	logger.InfoContext(ctx, "sent callback to target", "target", callback.Target, "status_code", resp.StatusCode)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("callback target responded with status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// callbackClient copies the http client with a transport using the callback's tls configuration
+func callbackClient(httpClient *http.Client, config *tls.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if t, ok := httpClient.Transport.(*http.Transport); ok {
+		transport = t.Clone()
+	}
+	transport.TLSClientConfig = config
+
+	client := *httpClient
+	client.Transport = transport
+	return &client
 }
@@ -0,0 +1,224 @@
+package assured
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// v2Path prefixes the resource oriented admin endpoints, after the admin prefix
+const v2Path = "/v2"
+
+// problem writes a problem details document titled by the status, with the detail
+func problem(w http.ResponseWriter, status int, detail string) {
+	_ = encodeProblem(w, Problem{Title: http.StatusText(status), Status: status, Detail: detail})
+}
+
+// keyFilter is the method and path query parameters that filter stubbed calls and records. The path is
+// filtered when the parameter is present, as an empty path is the root path.
+type keyFilter struct {
+	method  string
+	path    string
+	hasPath bool
+}
+
+// parseKeyFilter reads the method and path query parameters, which must form a valid request when set
+func parseKeyFilter(query url.Values) (keyFilter, error) {
+	filter := keyFilter{
+		method:  query.Get("method"),
+		path:    strings.Trim(query.Get("path"), "/"),
+		hasPath: query.Has("path"),
+	}
+	if filter.method != "" {
+		if _, err := http.NewRequest(filter.method, filter.path, nil); err != nil {
+			return keyFilter{}, err
+		}
+	}
+	return filter, nil
+}
+
+// matches returns whether the method and path match the filter's, when set
+func (f keyFilter) matches(method, path string) bool {
+	return (f.method == "" || f.method == method) && (!f.hasPath || f.path == path)
+}
+
+// key returns the store key of the filter, which must have both a method and a path to select a key
+func (f keyFilter) key() (string, error) {
+	switch {
+	case f.method == "" && !f.hasPath:
+		return "", nil
+	case f.method == "" || !f.hasPath:
+		return "", fmt.Errorf("method and path must be set together")
+	}
+	return fmt.Sprintf("%s:%s", f.method, f.path), nil
+}
+
+// handleListStubsV2 returns the stubbed calls, filtered by the method and path query parameters
+func handleListStubsV2(calls *Store[Call]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseKeyFilter(r.URL.Query())
+		if err != nil {
+			problem(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		stubs := slices.DeleteFunc(calls.All(), func(call Call) bool {
+			return !filter.matches(call.Method, call.Path)
+		})
+		_ = encode(w, http.StatusOK, stubs)
+	}
+}
+
+// handleDeleteStubsV2 removes the stubbed calls with the method and path query parameters, or every stubbed call
+func handleDeleteStubsV2(logger *slog.Logger, calls *Store[Call], callbacks *callbackScopes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := deleteKey(r)
+		if err != nil {
+			problem(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if key == "" {
+			callbacks.cancelCalls(calls.All())
+			calls.ClearAll()
+		} else {
+			callbacks.cancelCalls(calls.Get(key))
+			calls.Clear(key)
+		}
+		logger.InfoContext(r.Context(), "cleared assured calls", "key", key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleSwapStubsV2 atomically deletes the stubbed calls with the IDs and stubs the calls in their place, keeping
+// every other stubbed call. Nothing is changed unless every call is valid, and IDs that are not stubbed are ignored.
+func handleSwapStubsV2(
+	logger *slog.Logger,
+	httpClient *http.Client,
+	calls *Store[Call],
+	callbacks *callbackScopes,
+	stubSpec *OpenAPI,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		swap, err := decode[StubSwap](r)
+		if err != nil {
+			problem(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		stubs := slices.Clone(swap.Calls)
+		if stubs == nil {
+			stubs = []Call{}
+		}
+		for i := range stubs {
+			if err := validateCall(&stubs[i], httpClient); err != nil {
+				problem(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if !conformsToContract(w, r, logger, stubSpec, stubs[i]) {
+				return
+			}
+			stubs[i].ID = rand.Text()
+		}
+
+		removed := calls.Swap(func(call Call) bool { return slices.Contains(swap.Delete, call.ID) }, stubs)
+		callbacks.cancelCalls(removed)
+		logger.InfoContext(r.Context(), "assured calls swapped", "deleted", len(removed), "calls", len(stubs))
+		_ = encode(w, http.StatusOK, stubs)
+	}
+}
+
+// handleListRequestsV2 returns the records of the requests made, filtered by the method, path and
+// operation_name query parameters
+func handleListRequestsV2(records *Store[Record], trackRecords bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseKeyFilter(r.URL.Query())
+		if err != nil {
+			problem(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if !trackRecords {
+			problem(w, http.StatusNotFound, "tracking records is disabled")
+			return
+		}
+
+		operationName := r.URL.Query().Get("operation_name")
+		requests := slices.DeleteFunc(records.All(), func(record Record) bool {
+			if !filter.matches(record.Method, record.Path) {
+				return true
+			}
+			return operationName != "" && parseGraphQLRequest(record).OperationName != operationName
+		})
+		_ = encode(w, http.StatusOK, requests)
+	}
+}
+
+// handleDeleteRequestsV2 removes the records with the method and path query parameters, or every record
+func handleDeleteRequestsV2(logger *slog.Logger, records *Store[Record]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := deleteKey(r)
+		if err != nil {
+			problem(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if key == "" {
+			records.ClearAll()
+		} else {
+			records.Clear(key)
+		}
+		logger.InfoContext(r.Context(), "cleared assured records", "key", key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleDeleteSnapshotV2 removes the stubbed calls and records with the method and path query parameters, or every
+// stubbed call and record, in one request
+func handleDeleteSnapshotV2(logger *slog.Logger, calls *Store[Call], records *Store[Record], callbacks *callbackScopes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := deleteKey(r)
+		if err != nil {
+			problem(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if key == "" {
+			callbacks.cancelCalls(calls.All())
+			calls.ClearAll()
+			records.ClearAll()
+		} else {
+			callbacks.cancelCalls(calls.Get(key))
+			calls.Clear(key)
+			records.Clear(key)
+		}
+		logger.InfoContext(r.Context(), "cleared assured calls and records", "key", key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleDeleteCallbackV2 stops a triggered callback by its ID
+func handleDeleteCallbackV2(logger *slog.Logger, callbacks *callbackScopes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if !callbacks.cancelTriggered(id) {
+			problem(w, http.StatusNotFound, "assured callback not found")
+			return
+		}
+		logger.InfoContext(r.Context(), "assured callback cancelled", "id", id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// deleteKey returns the store key selected by the method and path query parameters, or an empty key for every key
+func deleteKey(r *http.Request) (string, error) {
+	filter, err := parseKeyFilter(r.URL.Query())
+	if err != nil {
+		return "", err
+	}
+	return filter.key()
+}
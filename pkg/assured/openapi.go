@@ -0,0 +1,297 @@
+package assured
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPI is a structure containing the parts of an OpenAPI 3 document used to stub and validate calls
+type OpenAPI struct {
+	OpenAPI    string               `yaml:"openapi"`
+	Servers    []OpenAPIServer      `yaml:"servers"`
+	Paths      map[string]*PathItem `yaml:"paths"`
+	Components Components           `yaml:"components"`
+}
+
+// OpenAPIServer is a structure containing an OpenAPI server
+type OpenAPIServer struct {
+	URL string `yaml:"url"`
+}
+
+// Components is a structure containing the reusable objects of an OpenAPI document
+type Components struct {
+	Schemas       map[string]*Schema      `yaml:"schemas"`
+	Responses     map[string]*Response    `yaml:"responses"`
+	Parameters    map[string]*Parameter   `yaml:"parameters"`
+	RequestBodies map[string]*RequestBody `yaml:"requestBodies"`
+	Headers       map[string]*Header      `yaml:"headers"`
+	Examples      map[string]*Example     `yaml:"examples"`
+}
+
+// PathItem is a structure containing the operations available on a single path
+type PathItem struct {
+	Parameters []*Parameter `yaml:"parameters"`
+	Get        *Operation   `yaml:"get"`
+	Put        *Operation   `yaml:"put"`
+	Post       *Operation   `yaml:"post"`
+	Delete     *Operation   `yaml:"delete"`
+	Options    *Operation   `yaml:"options"`
+	Head       *Operation   `yaml:"head"`
+	Patch      *Operation   `yaml:"patch"`
+	Trace      *Operation   `yaml:"trace"`
+}
+
+// Operations returns the path's operations keyed by their http method
+func (p *PathItem) Operations() map[string]*Operation {
+	operations := map[string]*Operation{}
+	for method, operation := range map[string]*Operation{
+		http.MethodGet:     p.Get,
+		http.MethodPut:     p.Put,
+		http.MethodPost:    p.Post,
+		http.MethodDelete:  p.Delete,
+		http.MethodOptions: p.Options,
+		http.MethodHead:    p.Head,
+		http.MethodPatch:   p.Patch,
+		http.MethodTrace:   p.Trace,
+	} {
+		if operation != nil {
+			operations[method] = operation
+		}
+	}
+	return operations
+}
+
+// Operation is a structure containing a single API operation on a path
+type Operation struct {
+	OperationID string               `yaml:"operationId"`
+	Parameters  []*Parameter         `yaml:"parameters"`
+	RequestBody *RequestBody         `yaml:"requestBody"`
+	Responses   map[string]*Response `yaml:"responses"`
+}
+
+// Parameter is a structure containing a path, query, header or cookie parameter
+type Parameter struct {
+	Ref      string              `yaml:"$ref"`
+	Name     string              `yaml:"name"`
+	In       string              `yaml:"in"`
+	Required bool                `yaml:"required"`
+	Schema   *Schema             `yaml:"schema"`
+	Example  any                 `yaml:"example"`
+	Examples map[string]*Example `yaml:"examples"`
+}
+
+// RequestBody is a structure containing the request body of an operation
+type RequestBody struct {
+	Ref      string                `yaml:"$ref"`
+	Required bool                  `yaml:"required"`
+	Content  map[string]*MediaType `yaml:"content"`
+}
+
+// Response is a structure containing a single response of an operation
+type Response struct {
+	Ref     string                `yaml:"$ref"`
+	Headers map[string]*Header    `yaml:"headers"`
+	Content map[string]*MediaType `yaml:"content"`
+}
+
+// Header is a structure containing a response header
+type Header struct {
+	Ref      string  `yaml:"$ref"`
+	Required bool    `yaml:"required"`
+	Schema   *Schema `yaml:"schema"`
+	Example  any     `yaml:"example"`
+}
+
+// MediaType is a structure containing the schema and examples for a content type
+type MediaType struct {
+	Schema   *Schema             `yaml:"schema"`
+	Example  any                 `yaml:"example"`
+	Examples map[string]*Example `yaml:"examples"`
+}
+
+// Example is a structure containing a named example value
+type Example struct {
+	Ref   string `yaml:"$ref"`
+	Value any    `yaml:"value"`
+}
+
+// Schema is a structure containing the subset of JSON schema used to describe OpenAPI values
+type Schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 SchemaType         `yaml:"type"`
+	Format               string             `yaml:"format"`
+	Nullable             bool               `yaml:"nullable"`
+	Properties           map[string]*Schema `yaml:"properties"`
+	Required             []string           `yaml:"required"`
+	AdditionalProperties *Schema            `yaml:"additionalProperties"`
+	Items                *Schema            `yaml:"items"`
+	Enum                 []any              `yaml:"enum"`
+	AllOf                []*Schema          `yaml:"allOf"`
+	OneOf                []*Schema          `yaml:"oneOf"`
+	AnyOf                []*Schema          `yaml:"anyOf"`
+	Example              any                `yaml:"example"`
+	Examples             []any              `yaml:"examples"`
+	Default              any                `yaml:"default"`
+	Minimum              *float64           `yaml:"minimum"`
+	Maximum              *float64           `yaml:"maximum"`
+	MinLength            *int               `yaml:"minLength"`
+	MaxLength            *int               `yaml:"maxLength"`
+	MinItems             *int               `yaml:"minItems"`
+	MaxItems             *int               `yaml:"maxItems"`
+
+	// Disallowed is set by a boolean false schema, which no value matches
+	Disallowed bool `yaml:"-"`
+}
+
+// UnmarshalYAML accepts boolean schemas, such as `additionalProperties: false`
+func (s *Schema) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode && value.Tag == "!!bool" {
+		*s = Schema{Disallowed: value.Value == "false"}
+		return nil
+	}
+	type schema Schema
+	return value.Decode((*schema)(s))
+}
+
+// SchemaType is the type, or list of types, a schema allows
+type SchemaType []string
+
+// UnmarshalYAML accepts both the single type of OpenAPI 3.0 and the list of types of OpenAPI 3.1
+func (t *SchemaType) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*t = SchemaType{value.Value}
+		return nil
+	}
+	var types []string
+	if err := value.Decode(&types); err != nil {
+		return err
+	}
+	*t = types
+	return nil
+}
+
+// Has returns true if the schema allows the type
+func (t SchemaType) Has(typ string) bool {
+	return slices.Contains(t, typ)
+}
+
+// LoadOpenAPI reads an OpenAPI 3 document from a YAML or JSON file
+func LoadOpenAPI(path string) (*OpenAPI, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read openapi file: %w", err)
+	}
+	return ParseOpenAPI(b)
+}
+
+// ParseOpenAPI parses an OpenAPI 3 document from YAML or JSON
+func ParseOpenAPI(b []byte) (*OpenAPI, error) {
+	var spec OpenAPI
+	if err := yaml.Unmarshal(b, &spec); err != nil {
+		return nil, fmt.Errorf("parse openapi: %w", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version %q", spec.OpenAPI)
+	}
+	return &spec, nil
+}
+
+// BasePath returns the path of the document's first server, which prefixes every path in the document
+func (o *OpenAPI) BasePath() string {
+	if len(o.Servers) == 0 || strings.Contains(o.Servers[0].URL, "{") {
+		return ""
+	}
+	u, err := url.Parse(o.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(u.Path, "/")
+}
+
+// component returns the name of a local component reference in the given section
+func component(ref, section string) (string, bool) {
+	return strings.CutPrefix(ref, "#/components/"+section+"/")
+}
+
+// schema resolves a schema's reference
+func (o *OpenAPI) schema(s *Schema) *Schema {
+	for i := 0; s != nil && s.Ref != "" && i < maxRefDepth; i++ {
+		name, _ := component(s.Ref, "schemas")
+		s = o.Components.Schemas[name]
+	}
+	return s
+}
+
+// parameter resolves a parameter's reference
+func (o *OpenAPI) parameter(p *Parameter) *Parameter {
+	for i := 0; p != nil && p.Ref != "" && i < maxRefDepth; i++ {
+		name, _ := component(p.Ref, "parameters")
+		p = o.Components.Parameters[name]
+	}
+	return p
+}
+
+// requestBody resolves a request body's reference
+func (o *OpenAPI) requestBody(b *RequestBody) *RequestBody {
+	for i := 0; b != nil && b.Ref != "" && i < maxRefDepth; i++ {
+		name, _ := component(b.Ref, "requestBodies")
+		b = o.Components.RequestBodies[name]
+	}
+	return b
+}
+
+// response resolves a response's reference
+func (o *OpenAPI) response(r *Response) *Response {
+	for i := 0; r != nil && r.Ref != "" && i < maxRefDepth; i++ {
+		name, _ := component(r.Ref, "responses")
+		r = o.Components.Responses[name]
+	}
+	return r
+}
+
+// header resolves a header's reference
+func (o *OpenAPI) header(h *Header) *Header {
+	for i := 0; h != nil && h.Ref != "" && i < maxRefDepth; i++ {
+		name, _ := component(h.Ref, "headers")
+		h = o.Components.Headers[name]
+	}
+	return h
+}
+
+// example resolves an example's reference
+func (o *OpenAPI) example(e *Example) *Example {
+	for i := 0; e != nil && e.Ref != "" && i < maxRefDepth; i++ {
+		name, _ := component(e.Ref, "examples")
+		e = o.Components.Examples[name]
+	}
+	return e
+}
+
+// parameters returns the resolved path and operation parameters, with operation parameters taking precedence
+func (o *OpenAPI) parameters(item *PathItem, operation *Operation) []*Parameter {
+	parameters := []*Parameter{}
+	index := map[string]int{}
+	for _, p := range append(append([]*Parameter{}, item.Parameters...), operation.Parameters...) {
+		p = o.parameter(p)
+		if p == nil {
+			continue
+		}
+		key := p.In + ":" + p.Name
+		if i, ok := index[key]; ok {
+			parameters[i] = p
+			continue
+		}
+		index[key] = len(parameters)
+		parameters = append(parameters, p)
+	}
+	return parameters
+}
+
+// maxRefDepth limits how deep references and nested schemas are followed
+const maxRefDepth = 16
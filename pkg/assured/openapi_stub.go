@@ -0,0 +1,311 @@
+package assured
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Calls generates a stubbed Call for every operation in the document. Templated paths, such as pets/{petId},
+// are stubbed as templates that match any value of their parameters, and responses use the lowest success
+// status code with its example, or a body synthesized from its schema when no example is given.
+func (o *OpenAPI) Calls() ([]Call, error) {
+	calls := []Call{}
+	for _, path := range sortedKeys(o.Paths) {
+		item := o.Paths[path]
+		if item == nil {
+			continue
+		}
+		operations := item.Operations()
+		for _, method := range sortedKeys(operations) {
+			call, err := o.stubCall(path, method, operations[method])
+			if err != nil {
+				return nil, fmt.Errorf("stub %s %s: %w", method, path, err)
+			}
+			calls = append(calls, call)
+		}
+	}
+	return calls, nil
+}
+
+// stubCall generates the stubbed Call for a single operation
+func (o *OpenAPI) stubCall(path, method string, operation *Operation) (Call, error) {
+	call := Call{
+		Path:       strings.Trim(strings.Join([]string{o.BasePath(), strings.Trim(path, "/")}, "/"), "/"),
+		Method:     method,
+		StatusCode: http.StatusOK,
+	}
+
+	status, response := o.stubResponse(operation)
+	if response == nil {
+		return call, nil
+	}
+	call.StatusCode = status
+
+	for _, name := range sortedKeys(response.Headers) {
+		header := o.header(response.Headers[name])
+		if header == nil {
+			continue
+		}
+		value := header.Example
+		if value == nil {
+			value = o.sample(header.Schema, 0)
+		}
+		if value != nil {
+			if call.Headers == nil {
+				call.Headers = map[string]string{}
+			}
+			call.Headers[name] = fmt.Sprint(value)
+		}
+	}
+
+	contentType, media := o.mediaType(response.Content)
+	if media == nil {
+		return call, nil
+	}
+	if call.Headers == nil {
+		call.Headers = map[string]string{}
+	}
+	call.Headers["Content-Type"] = contentType
+
+	value := o.mediaValue(media)
+	if s, ok := value.(string); ok && !isJSON(contentType) {
+		call.Response = []byte(s)
+		return call, nil
+	}
+	body, err := json.Marshal(value)
+	if err != nil {
+		return call, err
+	}
+	call.Response = body
+	return call, nil
+}
+
+// stubResponse selects the response to stub for an operation, preferring the lowest success status code
+func (o *OpenAPI) stubResponse(operation *Operation) (int, *Response) {
+	codes := sortedKeys(operation.Responses)
+	for _, prefer := range []func(code string) bool{
+		func(code string) bool { return len(code) == 3 && code[0] == '2' && code != "2XX" },
+		func(code string) bool { return strings.EqualFold(code, "2XX") || code == "default" },
+		func(string) bool { return true },
+	} {
+		for _, code := range codes {
+			if !prefer(code) {
+				continue
+			}
+			status, err := strconv.Atoi(code)
+			if err != nil {
+				status = http.StatusOK
+			}
+			return status, o.response(operation.Responses[code])
+		}
+	}
+	return http.StatusOK, nil
+}
+
+// mediaType selects the content type to stub, preferring JSON
+func (o *OpenAPI) mediaType(content map[string]*MediaType) (string, *MediaType) {
+	types := sortedKeys(content)
+	if len(types) == 0 {
+		return "", nil
+	}
+	if _, ok := content["application/json"]; ok {
+		return "application/json", content["application/json"]
+	}
+	for _, t := range types {
+		if isJSON(t) {
+			return t, content[t]
+		}
+	}
+	return types[0], content[types[0]]
+}
+
+// mediaValue returns the example value for a content type, or a value synthesized from its schema
+func (o *OpenAPI) mediaValue(media *MediaType) any {
+	if media.Example != nil {
+		return media.Example
+	}
+	for _, name := range sortedKeys(media.Examples) {
+		if example := o.example(media.Examples[name]); example != nil && example.Value != nil {
+			return example.Value
+		}
+	}
+	return o.sample(media.Schema, 0)
+}
+
+// sample synthesizes a value that satisfies a schema, using its examples where possible
+func (o *OpenAPI) sample(s *Schema, depth int) any {
+	s = o.schema(s)
+	if s == nil || s.Disallowed || depth > maxRefDepth {
+		return nil
+	}
+	switch {
+	case s.Example != nil:
+		return s.Example
+	case len(s.Examples) > 0:
+		return s.Examples[0]
+	case s.Default != nil:
+		return s.Default
+	case len(s.Enum) > 0:
+		return s.Enum[0]
+	case len(s.AllOf) > 0:
+		merged := map[string]any{}
+		for _, sub := range s.AllOf {
+			if m, ok := o.sample(sub, depth+1).(map[string]any); ok {
+				for k, v := range m {
+					merged[k] = v
+				}
+			}
+		}
+		for k, v := range o.sampleProperties(s, depth) {
+			merged[k] = v
+		}
+		return merged
+	case len(s.OneOf) > 0:
+		return o.sample(s.OneOf[0], depth+1)
+	case len(s.AnyOf) > 0:
+		return o.sample(s.AnyOf[0], depth+1)
+	}
+
+	switch schemaType(s) {
+	case "object":
+		return o.sampleProperties(s, depth)
+	case "array":
+		return o.sampleItems(s, depth)
+	case "string":
+		return sampleString(s)
+	case "integer":
+		return int64(sampleNumber(s, math.Ceil, math.Floor))
+	case "number":
+		return sampleNumber(s, nil, nil)
+	case "boolean":
+		return true
+	}
+	return nil
+}
+
+// sampleProperties synthesizes every property of an object schema
+func (o *OpenAPI) sampleProperties(s *Schema, depth int) map[string]any {
+	object := map[string]any{}
+	for name, property := range s.Properties {
+		object[name] = o.sample(property, depth+1)
+	}
+	return object
+}
+
+// sampleItems synthesizes an array of the fewest items its schema allows, with at least one item when allowed
+func (o *OpenAPI) sampleItems(s *Schema, depth int) []any {
+	count := 1
+	if s.MinItems != nil && *s.MinItems > count {
+		count = *s.MinItems
+	}
+	if s.MaxItems != nil && *s.MaxItems < count {
+		count = *s.MaxItems
+	}
+	item := o.sample(s.Items, depth+1)
+	if item == nil {
+		return []any{}
+	}
+	items := make([]any, count)
+	for i := range items {
+		items[i] = item
+	}
+	return items
+}
+
+// sampleNumber synthesizes the number closest to zero within a schema's minimum and maximum. The bounds are
+// rounded inwards when given, so integers stay within fractional bounds.
+func sampleNumber(s *Schema, roundMinimum, roundMaximum func(float64) float64) float64 {
+	n := 0.0
+	if s.Minimum != nil && n < *s.Minimum {
+		n = *s.Minimum
+		if roundMinimum != nil {
+			n = roundMinimum(n)
+		}
+	}
+	if s.Maximum != nil && n > *s.Maximum {
+		n = *s.Maximum
+		if roundMaximum != nil {
+			n = roundMaximum(n)
+		}
+	}
+	return n
+}
+
+// sampleString synthesizes a string for common formats, fitted to the schema's minimum and maximum length
+func sampleString(s *Schema) string {
+	value := formatString(s.Format)
+	length := utf8.RuneCountInString(value)
+	if s.MinLength != nil && length < *s.MinLength {
+		value += strings.Repeat("s", *s.MinLength-length)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		value = string([]rune(value)[:*s.MaxLength])
+	}
+	return value
+}
+
+// formatString synthesizes a string for common formats
+func formatString(format string) string {
+	switch format {
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "time":
+		return "00:00:00"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "email":
+		return "user@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	case "hostname":
+		return "example.com"
+	case "ipv4":
+		return "127.0.0.1"
+	case "ipv6":
+		return "::1"
+	case "byte":
+		return ""
+	}
+	return "string"
+}
+
+// schemaType returns the first non-null type of a schema, inferring objects and arrays from their keywords
+func schemaType(s *Schema) string {
+	for _, t := range s.Type {
+		if t != "null" {
+			return t
+		}
+	}
+	switch {
+	case s.Properties != nil || s.AdditionalProperties != nil:
+		return "object"
+	case s.Items != nil:
+		return "array"
+	}
+	return ""
+}
+
+// isJSON returns true for JSON content types, such as application/json or application/problem+json
+func isJSON(contentType string) bool {
+	contentType, _, _ = strings.Cut(contentType, ";")
+	contentType = strings.TrimSpace(strings.ToLower(contentType))
+	return contentType == "application/json" || strings.HasSuffix(contentType, "+json")
+}
+
+// sortedKeys returns a map's keys in a stable order
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,336 @@
+package assured
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOpenAPI(t *testing.T) {
+	spec, err := LoadOpenAPI("testdata/petstore.yaml")
+	require.NoError(t, err)
+	require.Equal(t, "3.0.3", spec.OpenAPI)
+	require.Equal(t, "v1", spec.BasePath())
+	require.Len(t, spec.Paths, 3)
+}
+
+func TestLoadOpenAPIMissing(t *testing.T) {
+	_, err := LoadOpenAPI("testdata/missing.yaml")
+	require.Error(t, err)
+	require.Equal(t, "read openapi file: open testdata/missing.yaml: no such file or directory", err.Error())
+}
+
+func TestParseOpenAPIUnsupportedVersion(t *testing.T) {
+	_, err := ParseOpenAPI([]byte(`{"swagger": "2.0"}`))
+	require.Error(t, err)
+	require.Equal(t, `unsupported openapi version ""`, err.Error())
+}
+
+func TestOpenAPICalls(t *testing.T) {
+	spec, err := LoadOpenAPI("testdata/petstore.yaml")
+	require.NoError(t, err)
+
+	calls, err := spec.Calls()
+	require.NoError(t, err)
+	require.Equal(t, []Call{
+		{
+			Path:       "v1/pets",
+			Method:     http.MethodGet,
+			StatusCode: http.StatusOK,
+			Headers:    map[string]string{"Content-Type": "application/json", "X-Next": "/pets?page=2"},
+			Response:   []byte(`[{"born":"2024-01-01","id":0,"name":"string","tag":"dog"}]`),
+		},
+		{
+			Path:       "v1/pets",
+			Method:     http.MethodPost,
+			StatusCode: http.StatusCreated,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Response:   []byte(`{"id":7,"name":"Rex","tag":"dog"}`),
+		},
+		{
+			Path:       "v1/pets/{petId}",
+			Method:     http.MethodDelete,
+			StatusCode: http.StatusNoContent,
+		},
+		{
+			Path:       "v1/pets/{petId}",
+			Method:     http.MethodGet,
+			StatusCode: http.StatusOK,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Response:   []byte(`{"born":"2024-01-01","id":0,"name":"string","tag":"dog"}`),
+		},
+		{
+			Path:       "v1/pets/{petId}/photo",
+			Method:     http.MethodGet,
+			StatusCode: http.StatusOK,
+			Headers:    map[string]string{"Content-Type": "text/plain"},
+			Response:   []byte("A very good dog"),
+		},
+	}, calls)
+}
+
+func TestOpenAPICallsOpenAPI31(t *testing.T) {
+	spec, err := ParseOpenAPI([]byte(`
+openapi: 3.1.0
+paths:
+  /status:
+    get:
+      responses:
+        2XX:
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  ok:
+                    type: [boolean, "null"]
+                  count:
+                    type: [integer, "null"]
+                    minimum: 3
+`))
+	require.NoError(t, err)
+
+	calls, err := spec.Calls()
+	require.NoError(t, err)
+	require.Equal(t, []Call{
+		{
+			Path:       "status",
+			Method:     http.MethodGet,
+			StatusCode: http.StatusOK,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Response:   []byte(`{"count":3,"ok":true}`),
+		},
+	}, calls)
+}
+
+func TestOpenAPICallsSchemaBounds(t *testing.T) {
+	spec, err := ParseOpenAPI([]byte(`
+openapi: 3.1.0
+paths:
+  /bounds:
+    get:
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  short:
+                    type: string
+                    maxLength: 3
+                  long:
+                    type: string
+                    minLength: 8
+                  negative:
+                    type: integer
+                    maximum: -5
+                  fraction:
+                    type: integer
+                    minimum: 2.5
+                  ratio:
+                    type: number
+                    minimum: 0.5
+                    maximum: 0.9
+                  pair:
+                    type: array
+                    minItems: 2
+                    items:
+                      type: boolean
+                  none:
+                    type: array
+                    maxItems: 0
+                    items:
+                      type: boolean
+`))
+	require.NoError(t, err)
+
+	calls, err := spec.Calls()
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	require.JSONEq(t, `{"short":"str","long":"stringss","negative":-5,"fraction":3,"ratio":0.5,"pair":[true,true],"none":[]}`,
+		string(calls[0].Response))
+	require.Empty(t, spec.ValidateCall(calls[0]))
+}
+
+func TestOpenAPIValidateRequest(t *testing.T) {
+	spec, err := LoadOpenAPI("testdata/petstore.yaml")
+	require.NoError(t, err)
+	requestID := map[string]string{"X-Request-Id": "00000000-0000-0000-0000-000000000000"}
+
+	tests := []struct {
+		name   string
+		record Record
+		want   []string
+	}{
+		{
+			name:   "conforming",
+			record: Record{Method: http.MethodGet, Path: "v1/pets", Headers: requestID, Query: map[string]string{"limit": "10"}},
+			want:   []string{},
+		},
+		{
+			name:   "unknown operation",
+			record: Record{Method: http.MethodPatch, Path: "v1/pets"},
+			want:   []string{"no operation matches PATCH /v1/pets"},
+		},
+		{
+			name:   "missing base path",
+			record: Record{Method: http.MethodGet, Path: "pets"},
+			want:   []string{"no operation matches GET /pets"},
+		},
+		{
+			name:   "invalid parameters",
+			record: Record{Method: http.MethodGet, Path: "v1/pets", Headers: map[string]string{"X-Request-Id": "nope"}, Query: map[string]string{"limit": "1000"}},
+			want:   []string{"query.limit: must be at most 100", "header.X-Request-ID: must be a uuid"},
+		},
+		{
+			name:   "missing required header",
+			record: Record{Method: http.MethodGet, Path: "v1/pets", Query: map[string]string{"limit": "ten"}},
+			want:   []string{"query.limit: must be of type integer", "header.X-Request-ID: is required"},
+		},
+		{
+			name:   "invalid path parameter",
+			record: Record{Method: http.MethodGet, Path: "v1/pets/rex"},
+			want:   []string{"path.petId: must be of type integer"},
+		},
+		{
+			name:   "templated path prefers the fewest parameters",
+			record: Record{Method: http.MethodGet, Path: "v1/pets/{petId}/photo"},
+			want:   []string{},
+		},
+		{
+			name:   "conforming body",
+			record: Record{Method: http.MethodPost, Path: "v1/pets", Headers: map[string]string{"Content-Type": "application/json; charset=utf-8"}, Body: []byte(`{"name":"Rex","tag":"dog"}`)},
+			want:   []string{},
+		},
+		{
+			name:   "missing body",
+			record: Record{Method: http.MethodPost, Path: "v1/pets"},
+			want:   []string{"body: is required"},
+		},
+		{
+			name:   "invalid body",
+			record: Record{Method: http.MethodPost, Path: "v1/pets", Headers: map[string]string{"Content-Type": "application/json"}, Body: []byte(`{"name":"","tag":"fish"}`)},
+			want:   []string{"body.name: must be at least 1 characters", "body.tag: must be one of [dog cat]"},
+		},
+		{
+			name:   "invalid json",
+			record: Record{Method: http.MethodPost, Path: "v1/pets", Headers: map[string]string{"Content-Type": "application/json"}, Body: []byte(`{"name":`)},
+			want:   []string{"body: invalid json: unexpected EOF"},
+		},
+		{
+			name:   "unsupported content type",
+			record: Record{Method: http.MethodPost, Path: "v1/pets", Headers: map[string]string{"Content-Type": "text/plain"}, Body: []byte(`Rex`)},
+			want:   []string{`body: content type "text/plain" is not allowed`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, spec.ValidateRequest(tt.record))
+		})
+	}
+}
+
+func TestOpenAPIValidateSchema(t *testing.T) {
+	spec, err := ParseOpenAPI([]byte(`
+openapi: 3.1.0
+components:
+  schemas:
+    Strict:
+      type: object
+      additionalProperties: false
+      properties:
+        id:
+          type: [integer, "null"]
+        tags:
+          type: array
+          maxItems: 1
+          items:
+            type: string
+    Either:
+      oneOf:
+        - type: string
+        - type: integer
+`))
+	require.NoError(t, err)
+
+	validate := func(name, body string) []string {
+		var value any
+		decoder := json.NewDecoder(strings.NewReader(body))
+		decoder.UseNumber()
+		require.NoError(t, decoder.Decode(&value))
+		return spec.validate(&Schema{Ref: "#/components/schemas/" + name}, value, "body", 0)
+	}
+
+	require.Empty(t, validate("Strict", `{"id": null, "tags": ["a"]}`))
+	require.Equal(t, []string{
+		"body.extra: is not allowed",
+		"body.id: must be of type integer or null",
+		"body.tags: must have at most 1 items",
+		"body.tags[1]: must be of type string",
+	}, validate("Strict", `{"id": 1.5, "tags": ["a", 2], "extra": true}`))
+	require.Empty(t, validate("Either", `7`))
+	require.Equal(t, []string{"body: must match exactly one schema in oneOf, matched 0"}, validate("Either", `true`))
+}
+
+func TestOpenAPIValidateCall(t *testing.T) {
+	spec, err := LoadOpenAPI("testdata/petstore.yaml")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		call Call
+		want []string
+	}{
+		{
+			name: "conforming",
+			call: Call{Method: http.MethodGet, Path: "v1/pets/7", Response: []byte(`{"id":7,"name":"Rex"}`)},
+			want: []string{},
+		},
+		{
+			name: "unknown operation",
+			call: Call{Method: http.MethodGet, Path: "v1/owners"},
+			want: []string{"no operation matches GET /v1/owners"},
+		},
+		{
+			name: "undocumented status code",
+			call: Call{Method: http.MethodDelete, Path: "v1/pets/7", StatusCode: http.StatusTeapot},
+			want: []string{"status_code: 418 is not a documented response"},
+		},
+		{
+			name: "content on a response without content",
+			call: Call{Method: http.MethodDelete, Path: "v1/pets/7", StatusCode: http.StatusNoContent, Response: []byte(`deleted`)},
+			want: []string{"response: status code 204 has no content"},
+		},
+		{
+			name: "invalid body",
+			call: Call{Method: http.MethodGet, Path: "v1/pets/7", Response: []byte(`{"id":"seven","born":"yesterday"}`)},
+			want: []string{"response.name: is required", "response.born: must be a date", "response.id: must be of type integer"},
+		},
+		{
+			name: "default response",
+			call: Call{Method: http.MethodGet, Path: "v1/pets", StatusCode: http.StatusInternalServerError, Headers: map[string]string{"content-type": "application/problem+json"}, Response: []byte(`{"status":500}`)},
+			want: []string{"response.title: is required"},
+		},
+		{
+			name: "undocumented content type",
+			call: Call{Method: http.MethodGet, Path: "v1/pets/7", Headers: map[string]string{"Content-Type": "application/xml"}, Response: []byte(`<pet/>`)},
+			want: []string{`response: content type "application/xml" is not allowed`},
+		},
+		{
+			name: "case insensitive headers",
+			call: Call{Method: http.MethodGet, Path: "v1/pets", Headers: map[string]string{"x-next": "2", "content-type": "application/json"}, Response: []byte(`[]`)},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, spec.ValidateCall(tt.call))
+		})
+	}
+}
@@ -0,0 +1,410 @@
+package assured
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationMode controls how calls that do not conform to an OpenAPI document are handled
+type ValidationMode string
+
+const (
+	// ValidationRecord serves non-conforming requests and records their violations
+	ValidationRecord ValidationMode = "record"
+	// ValidationReject rejects non-conforming requests with a 400 problem document
+	ValidationReject ValidationMode = "reject"
+)
+
+// pathTemplate matches the parameters in an OpenAPI path template, such as {petId}
+var pathTemplate = regexp.MustCompile(`\{([^}/]+)\}`)
+
+// uuidPattern matches the uuid string format
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// match is an operation matched to a method and path
+type match struct {
+	item       *PathItem
+	operation  *Operation
+	pathParams map[string]string
+}
+
+// findOperation returns the operation for a method and path, preferring paths with the fewest parameters
+func (o *OpenAPI) findOperation(method, path string) (match, bool) {
+	path = strings.Trim(path, "/")
+	if base := o.BasePath(); base != "" {
+		trimmed, ok := strings.CutPrefix(path, base)
+		if !ok || (trimmed != "" && trimmed[0] != '/') {
+			return match{}, false
+		}
+		path = strings.Trim(trimmed, "/")
+	}
+
+	best, found, fewest := match{}, false, -1
+	for _, template := range sortedKeys(o.Paths) {
+		item := o.Paths[template]
+		if item == nil {
+			continue
+		}
+		operation := item.Operations()[method]
+		if operation == nil {
+			continue
+		}
+		params, ok := matchPath(template, path)
+		if !ok || (found && len(params) >= fewest) {
+			continue
+		}
+		best, found, fewest = match{item: item, operation: operation, pathParams: params}, true, len(params)
+	}
+	return best, found
+}
+
+// matchPath matches a path against an OpenAPI path template, returning the path parameter values
+func matchPath(template, path string) (map[string]string, bool) {
+	names := []string{}
+	pattern := "^"
+	rest := strings.Trim(template, "/")
+	last := 0
+	for _, loc := range pathTemplate.FindAllStringSubmatchIndex(rest, -1) {
+		pattern += regexp.QuoteMeta(rest[last:loc[0]]) + "([^/]+)"
+		names = append(names, rest[loc[2]:loc[3]])
+		last = loc[1]
+	}
+	pattern += regexp.QuoteMeta(rest[last:]) + "$"
+
+	values := regexp.MustCompile(pattern).FindStringSubmatch(path)
+	if values == nil {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, name := range names {
+		params[name] = values[i+1]
+	}
+	return params, true
+}
+
+// ValidateRequest returns the ways a recorded request does not conform to the document
+func (o *OpenAPI) ValidateRequest(record Record) []string {
+	m, ok := o.findOperation(record.Method, record.Path)
+	if !ok {
+		return []string{fmt.Sprintf("no operation matches %s /%s", record.Method, strings.Trim(record.Path, "/"))}
+	}
+
+	violations := []string{}
+	for _, p := range o.parameters(m.item, m.operation) {
+		var value string
+		var present bool
+		switch p.In {
+		case "path":
+			value, present = m.pathParams[p.Name]
+		case "query":
+			value, present = record.Query[p.Name]
+		case "header":
+			value, present = record.Headers[http.CanonicalHeaderKey(p.Name)]
+		default:
+			continue
+		}
+		at := p.In + "." + p.Name
+		if !present {
+			if p.Required {
+				violations = append(violations, fmt.Sprintf("%s: is required", at))
+			}
+			continue
+		}
+		violations = append(violations, o.validate(p.Schema, o.coerce(p.Schema, value), at, 0)...)
+	}
+
+	body := o.requestBody(m.operation.RequestBody)
+	if body == nil {
+		return violations
+	}
+	if len(record.Body) == 0 {
+		if body.Required {
+			violations = append(violations, "body: is required")
+		}
+		return violations
+	}
+	return append(violations, o.validateContent(body.Content, record.Headers["Content-Type"], record.Body, "body")...)
+}
+
+// ValidateCall returns the ways a stubbed call's response does not conform to the document
+func (o *OpenAPI) ValidateCall(call Call) []string {
+	m, ok := o.findOperation(call.Method, call.Path)
+	if !ok {
+		return []string{fmt.Sprintf("no operation matches %s /%s", call.Method, strings.Trim(call.Path, "/"))}
+	}
+
+	status := call.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := o.findResponse(m.operation, status)
+	if response == nil {
+		return []string{fmt.Sprintf("status_code: %d is not a documented response", status)}
+	}
+
+	violations := []string{}
+	for _, name := range sortedKeys(response.Headers) {
+		header := o.header(response.Headers[name])
+		if header == nil || strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		at := "headers." + name
+		value, ok := headerValue(call.Headers, name)
+		if !ok {
+			if header.Required {
+				violations = append(violations, fmt.Sprintf("%s: is required", at))
+			}
+			continue
+		}
+		violations = append(violations, o.validate(header.Schema, o.coerce(header.Schema, value), at, 0)...)
+	}
+
+	body, err := call.source().read()
+	if err != nil {
+		return append(violations, fmt.Sprintf("response: %s", err))
+	}
+	if len(body) == 0 {
+		return violations
+	}
+	if len(response.Content) == 0 {
+		return append(violations, fmt.Sprintf("response: status code %d has no content", status))
+	}
+	contentType, ok := headerValue(call.Headers, "Content-Type")
+	if !ok && len(response.Content) == 1 {
+		contentType = sortedKeys(response.Content)[0]
+	}
+	return append(violations, o.validateContent(response.Content, contentType, body, "response")...)
+}
+
+// findResponse returns the response documented for a status code, falling back to its range and the default
+func (o *OpenAPI) findResponse(operation *Operation, status int) *Response {
+	code := strconv.Itoa(status)
+	for _, candidate := range []string{code, code[:1] + "XX", "default"} {
+		for key, response := range operation.Responses {
+			if strings.EqualFold(key, candidate) {
+				return o.response(response)
+			}
+		}
+	}
+	return nil
+}
+
+// headerValue returns a header's value, matching its name case-insensitively
+func headerValue(headers map[string]string, name string) (string, bool) {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// validateContent validates a body against the schema of its content type
+func (o *OpenAPI) validateContent(content map[string]*MediaType, contentType string, body []byte, at string) []string {
+	media, ok := findMediaType(content, contentType)
+	if !ok {
+		return []string{fmt.Sprintf("%s: content type %q is not allowed", at, contentType)}
+	}
+	if media == nil || media.Schema == nil || !isJSON(contentType) {
+		return nil
+	}
+
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return []string{fmt.Sprintf("%s: invalid json: %s", at, err)}
+	}
+	return o.validate(media.Schema, value, at, 0)
+}
+
+// findMediaType returns the media type for a content type, including wildcard media ranges
+func findMediaType(content map[string]*MediaType, contentType string) (*MediaType, bool) {
+	if len(content) == 0 {
+		return nil, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	major, _, _ := strings.Cut(mediaType, "/")
+	for _, candidate := range []string{mediaType, major + "/*", "*/*"} {
+		for key, media := range content {
+			if strings.EqualFold(key, candidate) {
+				return media, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// coerce converts a parameter's string value to the type of its schema
+func (o *OpenAPI) coerce(s *Schema, raw string) any {
+	s = o.schema(s)
+	if s == nil {
+		return raw
+	}
+	switch schemaType(s) {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err == nil {
+			return json.Number(raw)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	case "array":
+		values := []any{}
+		for _, v := range strings.Split(raw, ",") {
+			values = append(values, o.coerce(s.Items, v))
+		}
+		return values
+	}
+	return raw
+}
+
+// validate returns the ways a decoded JSON value does not conform to a schema
+func (o *OpenAPI) validate(s *Schema, value any, at string, depth int) []string {
+	s = o.schema(s)
+	if s == nil || depth > maxRefDepth {
+		return nil
+	}
+	if s.Disallowed {
+		return []string{fmt.Sprintf("%s: is not allowed", at)}
+	}
+	if value == nil {
+		if s.Nullable || s.Type.Has("null") || len(s.Type) == 0 {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: must not be null", at)}
+	}
+
+	violations := []string{}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return equalJSON(e, value) }) {
+		violations = append(violations, fmt.Sprintf("%s: must be one of %v", at, s.Enum))
+	}
+	for _, sub := range s.AllOf {
+		violations = append(violations, o.validate(sub, value, at, depth+1)...)
+	}
+	if len(s.OneOf) > 0 {
+		matched := 0
+		for _, sub := range s.OneOf {
+			if len(o.validate(sub, value, at, depth+1)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			violations = append(violations, fmt.Sprintf("%s: must match exactly one schema in oneOf, matched %d", at, matched))
+		}
+	}
+	if len(s.AnyOf) > 0 && !slices.ContainsFunc(s.AnyOf, func(sub *Schema) bool { return len(o.validate(sub, value, at, depth+1)) == 0 }) {
+		violations = append(violations, fmt.Sprintf("%s: must match at least one schema in anyOf", at))
+	}
+
+	types := s.Type
+	if len(types) == 0 {
+		if t := schemaType(s); t != "" {
+			types = SchemaType{t}
+		}
+	}
+	if len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return isType(value, t) }) {
+		return append(violations, fmt.Sprintf("%s: must be of type %s", at, strings.Join(types, " or ")))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				violations = append(violations, fmt.Sprintf("%s.%s: is required", at, name))
+			}
+		}
+		for _, name := range sortedKeys(v) {
+			if property, ok := s.Properties[name]; ok {
+				violations = append(violations, o.validate(property, v[name], at+"."+name, depth+1)...)
+			} else if s.AdditionalProperties != nil {
+				violations = append(violations, o.validate(s.AdditionalProperties, v[name], at+"."+name, depth+1)...)
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			violations = append(violations, fmt.Sprintf("%s: must have at least %d items", at, *s.MinItems))
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			violations = append(violations, fmt.Sprintf("%s: must have at most %d items", at, *s.MaxItems))
+		}
+		for i, item := range v {
+			violations = append(violations, o.validate(s.Items, item, fmt.Sprintf("%s[%d]", at, i), depth+1)...)
+		}
+	case string:
+		if s.MinLength != nil && utf8.RuneCountInString(v) < *s.MinLength {
+			violations = append(violations, fmt.Sprintf("%s: must be at least %d characters", at, *s.MinLength))
+		}
+		if s.MaxLength != nil && utf8.RuneCountInString(v) > *s.MaxLength {
+			violations = append(violations, fmt.Sprintf("%s: must be at most %d characters", at, *s.MaxLength))
+		}
+		if !isFormat(v, s.Format) {
+			violations = append(violations, fmt.Sprintf("%s: must be a %s", at, s.Format))
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		if s.Minimum != nil && n < *s.Minimum {
+			violations = append(violations, fmt.Sprintf("%s: must be at least %v", at, *s.Minimum))
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			violations = append(violations, fmt.Sprintf("%s: must be at most %v", at, *s.Maximum))
+		}
+	}
+	return violations
+}
+
+// isType returns true if a decoded JSON value is of a schema type
+func isType(value any, typ string) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		return typ == "object"
+	case []any:
+		return typ == "array"
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case json.Number:
+		if typ == "number" {
+			return true
+		}
+		_, err := v.Int64()
+		return typ == "integer" && err == nil
+	}
+	return false
+}
+
+// isFormat returns true if a string conforms to a checked string format
+func isFormat(value, format string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "date":
+		_, err := time.Parse(time.DateOnly, value)
+		return err == nil
+	case "uuid":
+		return uuidPattern.MatchString(value)
+	}
+	return true
+}
+
+// equalJSON compares a document value, such as an enum, to a decoded JSON value
+func equalJSON(a, b any) bool {
+	ab, errA := json.Marshal(a)
+	bb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ab, bb)
+}
@@ -0,0 +1,244 @@
+package assured
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Preload is the expected format for preloading assured endpoints through the go rest assured application
+type Preload struct {
+	Calls     []Call     `json:"calls"`
+	Callbacks []Callback `json:"callbacks"`
+}
+
+// LoadPreload reads and merges, in order, the preload files matched by each path. A path may be a file,
+// a glob pattern, or a directory whose JSON and YAML files are read recursively in lexical order.
+func LoadPreload(paths ...string) (Preload, error) {
+	preload := Preload{}
+	for _, path := range paths {
+		files, err := preloadFiles(path)
+		if err != nil {
+			return Preload{}, err
+		}
+		for _, file := range files {
+			p, err := readPreloadFile(file)
+			if err != nil {
+				return Preload{}, err
+			}
+			preload.Calls = append(preload.Calls, p.Calls...)
+			preload.Callbacks = append(preload.Callbacks, p.Callbacks...)
+		}
+	}
+	return preload, nil
+}
+
+// WatchPreload polls the preload paths every interval until the context is done. The stubs are the calls stubbed
+// from the preload files, with their server assigned IDs. When the loaded calls change and the new files are valid,
+// the stubs from the previous load are atomically swapped for the new calls, keeping any other stubs, such as
+// those generated from an OpenAPI document or stubbed through the admin API, and any records. If the server rejects
+// any new call, nothing is changed. Preload callbacks are not triggered again when reloading.
+func WatchPreload(ctx context.Context, client *Client, logger *slog.Logger, interval time.Duration, stubs []Call, paths ...string) {
+	current, err := LoadPreload(paths...)
+	if err != nil {
+		logger.InfoContext(ctx, "failed to load preload files", "error", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		preload, err := LoadPreload(paths...)
+		if err != nil {
+			logger.InfoContext(ctx, "invalid preload files, keeping current calls", "error", err)
+			continue
+		}
+		if reflect.DeepEqual(preload.Calls, current.Calls) {
+			continue
+		}
+
+		ids := make([]string, 0, len(stubs))
+		for _, stub := range stubs {
+			ids = append(ids, stub.ID)
+		}
+		reloaded, err := client.SwapStubs(ctx, ids, preload.Calls...)
+		if err != nil {
+			logger.InfoContext(ctx, "invalid preload calls, keeping current calls", "error", err)
+			continue
+		}
+		added, removed := diffCalls(current.Calls, preload.Calls)
+		current, stubs = preload, reloaded
+		logger.InfoContext(ctx, "reloaded preload files", "calls", len(preload.Calls), "added", added, "removed", removed)
+	}
+}
+
+// diffCalls returns the keys of the calls added and removed between two sets of calls, with their methods and
+// paths as the server stubs them
+func diffCalls(before, after []Call) ([]string, []string) {
+	counts := map[string]int{}
+	keys := map[string]string{}
+	for _, call := range before {
+		call = normalizeCall(call)
+		b, _ := json.Marshal(call)
+		counts[string(b)]--
+		keys[string(b)] = call.Key()
+	}
+	for _, call := range after {
+		call = normalizeCall(call)
+		b, _ := json.Marshal(call)
+		counts[string(b)]++
+		keys[string(b)] = call.Key()
+	}
+
+	added, removed := []string{}, []string{}
+	for _, call := range sortedKeys(counts) {
+		for ; counts[call] > 0; counts[call]-- {
+			added = append(added, keys[call])
+		}
+		for ; counts[call] < 0; counts[call]++ {
+			removed = append(removed, keys[call])
+		}
+	}
+	return added, removed
+}
+
+// normalizeCall defaults the call's method and trims its path, as the server does when stubbing it
+func normalizeCall(call Call) Call {
+	if call.Method == "" {
+		call.Method = http.MethodGet
+	}
+	call.Path = strings.Trim(call.Path, "/")
+	return call
+}
+
+// preloadFiles expands a preload path into the files it refers to
+func preloadFiles(path string) ([]string, error) {
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid preload pattern %s: %w", path, err)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no preload files match %s", path)
+	}
+
+	files := []string{}
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return nil, fmt.Errorf("read preload file: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, match)
+			continue
+		}
+		err = filepath.WalkDir(match, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && isPreloadFile(file) {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read preload directory: %w", err)
+		}
+	}
+	return files, nil
+}
+
+// isPreloadFile returns true for the file extensions read from preload directories
+func isPreloadFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// readPreloadFile reads a JSON or YAML preload file, which may contain a Preload, a list of Calls, or a single Call
+func readPreloadFile(path string) (Preload, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Preload{}, fmt.Errorf("read preload file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var v any
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return Preload{}, fmt.Errorf("parse preload file %s: %w", path, err)
+		}
+		if b, err = json.Marshal(v); err != nil {
+			return Preload{}, fmt.Errorf("parse preload file %s: %w", path, err)
+		}
+	}
+
+	var preload Preload
+	b = bytes.TrimSpace(b)
+	switch {
+	case bytes.HasPrefix(b, []byte("[")):
+		err = json.Unmarshal(b, &preload.Calls)
+	case isPreloadDocument(b):
+		err = json.Unmarshal(b, &preload)
+	default:
+		var call Call
+		err = json.Unmarshal(b, &call)
+		preload.Calls = []Call{call}
+	}
+	if err != nil {
+		return Preload{}, fmt.Errorf("parse preload file %s: %w", path, err)
+	}
+
+	// Resolve body files relative to the preload file
+	dir := filepath.Dir(path)
+	for i := range preload.Calls {
+		preload.Calls[i].BodyFile = resolvePath(dir, preload.Calls[i].BodyFile)
+		for j := range preload.Calls[i].Callbacks {
+			preload.Calls[i].Callbacks[j].BodyFile = resolvePath(dir, preload.Calls[i].Callbacks[j].BodyFile)
+		}
+	}
+	for i := range preload.Callbacks {
+		preload.Callbacks[i].BodyFile = resolvePath(dir, preload.Callbacks[i].BodyFile)
+	}
+	return preload, nil
+}
+
+// resolvePath resolves a relative path against a directory
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	if abs, err := filepath.Abs(filepath.Join(dir, path)); err == nil {
+		return abs
+	}
+	return filepath.Join(dir, path)
+}
+
+// isPreloadDocument returns true if a JSON object has the Preload fields, rather than being a single Call
+func isPreloadDocument(b []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return false
+	}
+	_, calls := fields["calls"]
+	_, callbacks := fields["callbacks"]
+	_, path := fields["path"]
+	return calls || (callbacks && !path)
+}
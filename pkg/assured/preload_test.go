@@ -0,0 +1,165 @@
+package assured
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPreload(t *testing.T) {
+	accounts := Call{Path: "accounts", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte(`{"accounts": []}`)}
+	payments := []Call{
+		{Path: "payments", Method: http.MethodPost, StatusCode: http.StatusCreated, Response: []byte("{\"id\": \"pay_1\", \"status\": \"created\"}\n")},
+		{Path: "payments/pay_1", Method: http.MethodGet, StatusCode: http.StatusOK},
+	}
+	account, err := filepath.Abs("testdata/responses/account.json")
+	require.NoError(t, err)
+	refunds := Call{
+		Path:       "payments/pay_1/refunds",
+		Method:     http.MethodPost,
+		StatusCode: http.StatusCreated,
+		BodyFile:   account,
+		Callbacks: []Callback{
+			{Target: "http://localhost:9000/events", Method: http.MethodPost, JSONBody: json.RawMessage(`{"type":"refund.created"}`)},
+		},
+	}
+	teapot := Call{Path: "teapot/assured", Method: http.MethodPost, StatusCode: http.StatusTeapot, Headers: map[string]string{"Content-Type": "text/plain"}, Response: []byte("I'm a teapot")}
+	callback := Callback{Target: "http://localhost:9000/events", Method: http.MethodPost, Interval: 30}
+
+	tests := []struct {
+		name  string
+		paths []string
+		want  Preload
+	}{
+		{
+			name:  "json file",
+			paths: []string{"testdata/preload/accounts.json"},
+			want:  Preload{Calls: []Call{accounts}, Callbacks: []Callback{callback}},
+		},
+		{
+			name:  "yaml file with a single call",
+			paths: []string{"testdata/preload/teapot.yaml"},
+			want:  Preload{Calls: []Call{teapot}},
+		},
+		{
+			name:  "yaml file with a list of calls",
+			paths: []string{"testdata/preload/payments/payments.yml"},
+			want:  Preload{Calls: payments},
+		},
+		{
+			name:  "yaml file with a relative body file",
+			paths: []string{"testdata/preload/payments/refunds.yaml"},
+			want:  Preload{Calls: []Call{refunds}},
+		},
+		{
+			name:  "directory",
+			paths: []string{"testdata/preload"},
+			want:  Preload{Calls: append(append([]Call{accounts}, payments...), refunds, teapot), Callbacks: []Callback{callback}},
+		},
+		{
+			name:  "glob",
+			paths: []string{"testdata/preload/*.yaml", "testdata/preload/*.json"},
+			want:  Preload{Calls: []Call{teapot, accounts}, Callbacks: []Callback{callback}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preload, err := LoadPreload(tt.paths...)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, preload)
+		})
+	}
+}
+
+func TestLoadPreloadFailure(t *testing.T) {
+	_, err := LoadPreload("testdata/preload/missing.json")
+	require.Error(t, err)
+	require.Equal(t, "no preload files match testdata/preload/missing.json", err.Error())
+
+	_, err = LoadPreload("testdata/preload/[")
+	require.Error(t, err)
+	require.Equal(t, "invalid preload pattern testdata/preload/[: syntax error in pattern", err.Error())
+
+	_, err = LoadPreload("testdata/localhost.pem")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "parse preload file testdata/localhost.pem")
+}
+
+func TestWatchPreload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "stubs.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("- path: watched\n  status_code: 200\n"), 0o600))
+
+	assured, err := ServeAssured(t.Context())
+	require.NoError(t, err)
+	defer func() { _ = assured.Close() }()
+	time.Sleep(time.Second)
+
+	preload, err := LoadPreload(dir)
+	require.NoError(t, err)
+	stubs, err := assured.SwapStubs(t.Context(), nil, preload.Calls...)
+	require.NoError(t, err)
+	require.NoError(t, assured.Given(t.Context(), Call{Path: "runtime", StatusCode: http.StatusTeapot}))
+	go WatchPreload(t.Context(), assured.Client, slog.Default(), 50*time.Millisecond, stubs, dir)
+
+	resp, err := http.Get(assured.URL() + "/watched")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// invalid files keep the current calls
+	require.NoError(t, os.WriteFile(file, []byte("- path: [watched\n"), 0o600))
+	time.Sleep(200 * time.Millisecond)
+	resp, err = http.Get(assured.URL() + "/watched")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// rejected calls keep the current calls, without stubbing the valid ones
+	require.NoError(t, os.WriteFile(file, []byte("- path: watched\n  status_code: 201\n- path: rejected\n  method: \"\\\"\"\n"), 0o600))
+	time.Sleep(200 * time.Millisecond)
+	resp, err = http.Get(assured.URL() + "/watched")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	all, err := assured.Stubs(t.Context())
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	require.NoError(t, os.WriteFile(file, []byte("- path: watched\n  status_code: 202\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "more.json"), []byte(`{"path": "added", "status_code": 201}`), 0o600))
+	time.Sleep(200 * time.Millisecond)
+
+	resp, err = http.Get(assured.URL() + "/watched")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	resp, err = http.Get(assured.URL() + "/added")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp, err = http.Get(assured.URL() + "/runtime")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTeapot, resp.StatusCode, "stubs not loaded from the preload files should be kept")
+	all, err = assured.Stubs(t.Context())
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+
+	records, err := assured.Verify(t.Context(), http.MethodGet, "watched")
+	require.NoError(t, err)
+	require.Len(t, records, 4, "records should be kept when reloading")
+}
+
+func TestDiffCalls(t *testing.T) {
+	before := []Call{*testCall1(), *testCall2(), *testCall2()}
+	after := []Call{*testCall2(), *testCall3()}
+
+	added, removed := diffCalls(before, after)
+	require.Equal(t, []string{"POST:teapot/assured"}, added)
+	require.ElementsMatch(t, []string{"GET:test/assured", "GET:test/assured"}, removed)
+
+	added, removed = diffCalls([]Call{{Path: "/added", Method: http.MethodGet}}, []Call{{Path: "added"}, {Path: "watched/"}})
+	require.Equal(t, []string{"GET:watched"}, added)
+	require.Empty(t, removed)
+}
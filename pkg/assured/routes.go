@@ -1,33 +1,125 @@
 package assured
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 )
 
 func routes(
+	ctx context.Context,
 	logger *slog.Logger,
 	calls *Store[Call],
 	records *Store[Record],
+	callbacks *callbackScopes,
 	httpClient *http.Client,
 	trackRecords bool,
+	validation *requestValidation,
+	stubSpec *OpenAPI,
+	auth *adminAuth,
+	prefix string,
 ) *http.ServeMux {
 	mux := http.NewServeMux()
+	when := handleWhen(ctx, logger, httpClient, calls, records, callbacks, trackRecords, validation)
+	admin := func(method, path string, handler http.HandlerFunc) {
+		pattern := prefix + path
+		if method != "" {
+			pattern = method + " " + pattern
+		}
+		mux.Handle(pattern, adminOnly(when, requireAdminAuth(auth, apiError, handler)))
+	}
+	resource := func(path string, handlers map[string]http.HandlerFunc) {
+		pattern := prefix + v2Path + path
+		for method, handler := range handlers {
+			mux.Handle(method+" "+pattern, adminOnly(when, requireAdminAuth(auth, problem, handler)))
+		}
+		allow := slices.Sorted(maps.Keys(handlers))
+		mux.Handle(pattern, adminOnly(when, requireAdminAuth(auth, problem, methodNotAllowed(problem, allow...))))
+	}
+
+	mux.Handle(prefix+"/health", adminOnly(when, http.HandlerFunc(handleHealth)))
+	admin("", "/given", handleGiven(logger, httpClient, calls, stubSpec, apiError, http.StatusOK))
+	admin(http.MethodGet, "/stubs", handleStubs(calls))
+	admin(http.MethodPut, "/stubs", handleReplaceStubs(logger, httpClient, calls, callbacks, stubSpec, apiError))
+	admin(http.MethodGet, "/stubs/{id}", handleGetStub(calls, apiError))
+	admin(http.MethodPut, "/stubs/{id}", handleUpdateStub(logger, httpClient, calls, callbacks, stubSpec, apiError))
+	admin(http.MethodDelete, "/stubs/{id}", handleDeleteStub(logger, calls, callbacks, apiError, http.StatusOK))
+	admin(http.MethodGet, "/snapshot", handleSnapshot(calls, records))
+	admin(http.MethodPost, "/restore", handleRestore(logger, httpClient, calls, records, callbacks, stubSpec, apiError, http.StatusOK))
+	admin(http.MethodPost, "/trigger", handleTrigger(logger, httpClient, callbacks, apiError, http.StatusOK))
+	admin("", "/trigger", methodNotAllowed(apiError, http.MethodPost))
+	admin("", "/verify", handleVerify(records, trackRecords))
+	admin(http.MethodPost, "/clear", handleClear(logger, calls, records, callbacks))
+	admin("", "/clear", methodNotAllowed(apiError, http.MethodPost))
+	admin(http.MethodPost, "/clearall", handleClearAll(logger, calls, records, callbacks))
+	admin("", "/clearall", methodNotAllowed(apiError, http.MethodPost))
 
-	mux.HandleFunc("/assured/health", handleHealth)
-	mux.HandleFunc("/assured/given", handleGiven(logger, calls))
-	mux.HandleFunc("/assured/verify", handleVerify(records, trackRecords))
-	mux.HandleFunc("/assured/clear", handleClear(logger, calls, records))
-	mux.HandleFunc("/assured/clearall", handleClearAll(logger, calls, records))
-	mux.HandleFunc("/", handleWhen(logger, httpClient, calls, records, trackRecords))
+	resource("/stubs", map[string]http.HandlerFunc{
+		http.MethodGet:    handleListStubsV2(calls),
+		http.MethodPost:   handleGiven(logger, httpClient, calls, stubSpec, problem, http.StatusCreated),
+		http.MethodPut:    handleReplaceStubs(logger, httpClient, calls, callbacks, stubSpec, problem),
+		http.MethodPatch:  handleSwapStubsV2(logger, httpClient, calls, callbacks, stubSpec),
+		http.MethodDelete: handleDeleteStubsV2(logger, calls, callbacks),
+	})
+	resource("/stubs/{id}", map[string]http.HandlerFunc{
+		http.MethodGet:    handleGetStub(calls, problem),
+		http.MethodPut:    handleUpdateStub(logger, httpClient, calls, callbacks, stubSpec, problem),
+		http.MethodDelete: handleDeleteStub(logger, calls, callbacks, problem, http.StatusNoContent),
+	})
+	resource("/requests", map[string]http.HandlerFunc{
+		http.MethodGet:    handleListRequestsV2(records, trackRecords),
+		http.MethodDelete: handleDeleteRequestsV2(logger, records),
+	})
+	resource("/snapshot", map[string]http.HandlerFunc{
+		http.MethodGet:    handleSnapshot(calls, records),
+		http.MethodPut:    handleRestore(logger, httpClient, calls, records, callbacks, stubSpec, problem, http.StatusNoContent),
+		http.MethodDelete: handleDeleteSnapshotV2(logger, calls, records, callbacks),
+	})
+	resource("/callbacks", map[string]http.HandlerFunc{
+		http.MethodPost: handleTrigger(logger, httpClient, callbacks, problem, http.StatusAccepted),
+	})
+	resource("/callbacks/{id}", map[string]http.HandlerFunc{
+		http.MethodDelete: handleDeleteCallbackV2(logger, callbacks),
+	})
+	mux.Handle(prefix+v2Path+"/", adminOnly(when, requireAdminAuth(auth, problem, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem(w, http.StatusNotFound, fmt.Sprintf("unknown resource %s", r.URL.Path))
+	}))))
+	mux.HandleFunc("/", when)
 
 	return mux
 }
 
+// stubsOnlyKey marks the context of requests to a listener that serves only stubbed endpoints
+type stubsOnlyKey struct{}
+
+// stubsOnly returns whether the request was made to a listener that serves only stubbed endpoints
+func stubsOnly(r *http.Request) bool {
+	only, _ := r.Context().Value(stubsOnlyKey{}).(bool)
+	return only
+}
+
+// adminOnly serves the admin handler, or the stub handler to requests made to a listener that serves only
+// stubbed endpoints, where the admin paths may be stubbed
+func adminOnly(stub, admin http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if stubsOnly(r) {
+			stub.ServeHTTP(w, r)
+			return
+		}
+		admin.ServeHTTP(w, r)
+	})
+}
+
 func decode[T any](r *http.Request) (T, error) {
 	var v T
 	if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
@@ -45,11 +137,25 @@ func encode[T any](w http.ResponseWriter, status int, v T) error {
 	return nil
 }
 
+// encodeProblem writes a problem details document to the http response
+func encodeProblem(w http.ResponseWriter, problem Problem) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		return fmt.Errorf("encode json: %w", err)
+	}
+	return nil
+}
+
 // decodeAssuredRecord converts an http request into an assured Record object
 func decodeAssuredRecord(req *http.Request) Record {
 	record := Record{
-		Path:   strings.Trim(req.URL.Path, "/"),
-		Method: req.Method,
+		Path:     strings.Trim(req.URL.Path, "/"),
+		Method:   req.Method,
+		Protocol: req.Proto,
+	}
+	if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+		record.ClientCert = req.TLS.PeerCertificates[0].Subject.String()
 	}
 
 	// Set headers
@@ -78,19 +184,108 @@ func decodeAssuredRecord(req *http.Request) Record {
 }
 
 // encodeAssuredCall writes the assured Call to the http response as it is intended to be stubbed
-func encodeAssuredCall(w http.ResponseWriter, i interface{}) error {
+func encodeAssuredCall(w http.ResponseWriter, r *http.Request, i interface{}) error {
 	switch resp := i.(type) {
 	case Call:
+		if resp.BodyFile != "" {
+			return encodeBodyFile(w, r, resp)
+		}
+		if len(resp.Chunks) > 0 {
+			return encodeChunks(w, r, resp)
+		}
+		body, err := resp.source().read()
+		if err != nil {
+			return encode(w, http.StatusInternalServerError, APIError{err.Error()})
+		}
+		if resp.GraphQL.hasPayload() {
+			w.Header().Set("Content-Type", "application/json")
+		}
 		for key, value := range resp.Headers {
 			w.Header().Set(key, value)
 		}
 		if resp.StatusCode > 0 {
 			w.WriteHeader(resp.StatusCode)
 		}
-		_, _ = w.Write([]byte(resp.String()))
+		_, _ = w.Write(body)
 	case []Record:
 		w.Header().Set("Content-Type", "application/json")
 		return json.NewEncoder(w).Encode(resp)
 	}
 	return nil
 }
+
+// encodeBodyFile streams the assured Call's body file from disk. The Content-Type is sniffed when it is not stubbed,
+// and range and conditional requests are supported when the stubbed status code is 200 OK.
+func encodeBodyFile(w http.ResponseWriter, r *http.Request, call Call) error {
+	file, err := os.Open(call.BodyFile)
+	if err != nil {
+		return encode(w, http.StatusInternalServerError, APIError{fmt.Sprintf("read body_file: %s", err)})
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return encode(w, http.StatusInternalServerError, APIError{fmt.Sprintf("read body_file: %s", err)})
+	}
+
+	for key, value := range call.Headers {
+		w.Header().Set(key, value)
+	}
+	if call.StatusCode == 0 || call.StatusCode == http.StatusOK {
+		http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+		return nil
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		contentType := mime.TypeByExtension(filepath.Ext(info.Name()))
+		if contentType == "" {
+			sniff := make([]byte, 512)
+			n, _ := io.ReadFull(file, sniff)
+			contentType = http.DetectContentType(sniff[:n])
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return encode(w, http.StatusInternalServerError, APIError{fmt.Sprintf("read body_file: %s", err)})
+			}
+		}
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	w.WriteHeader(call.StatusCode)
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// encodeChunks streams the assured Call's chunks, flushing each chunk after its delay
+// until every chunk is written or the client goes away
+func encodeChunks(w http.ResponseWriter, r *http.Request, call Call) error {
+	for key, value := range call.Headers {
+		w.Header().Set(key, value)
+	}
+	w.Header().Del("Content-Length")
+	if w.Header().Get("Content-Type") == "" {
+		switch call.ChunkFormat {
+		case ChunkFormatSSE:
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+		case ChunkFormatNDJSON:
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+	}
+	status := call.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+
+	flusher, _ := w.(http.Flusher)
+	for _, chunk := range call.Chunks {
+		if err := sleep(r.Context(), chunk.DelayMS); err != nil {
+			return err
+		}
+		if _, err := w.Write(chunk.Encode(call.ChunkFormat)); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
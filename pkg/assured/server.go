@@ -2,17 +2,42 @@ package assured
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 type Server struct {
 	ServerOptions
-	listener net.Listener
-	router   *http.ServeMux
-	calls    *Store[Call]
-	records  *Store[Record]
+	listener  net.Listener
+	server    *http.Server
+	router    *http.ServeMux
+	calls     *Store[Call]
+	records   *Store[Record]
+	callbacks *callbackScopes
+	ctx       context.Context
+	cancel    context.CancelFunc
+
+	tlsListener net.Listener
+	tlsServer   *http.Server
+
+	adminListener net.Listener
+	adminServer   *http.Server
+
+	grpcListener net.Listener
+	grpcServer   *grpc.Server
+
+	virtualServices []*virtualService
+
+	tlsConfig  *tls.Config
+	ca         *certificateAuthority
+	clientCert tls.Certificate
 }
 
 // NewServer creates a new go-rest-assured server
@@ -23,9 +48,24 @@ func NewServer(opts ...ServerOption) *Server {
 		records:       NewStore[Record](),
 	}
 	s.applyOptions(opts...)
-	s.router = routes(s.logger, s.calls, s.records, s.httpClient, s.trackRecords)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.callbacks = newCallbackScopes(s.ctx)
 
 	var err error
+	s.tlsConfig, err = s.newTLSConfig()
+	if err != nil {
+		s.logger.Error("unable to create tls config", "error", err)
+	}
+
+	// The port is served with tls, unless https is served on its own port alongside http
+	portTLS := s.tlsConfig
+	if s.httpAndHTTPS {
+		portTLS = nil
+	}
+
+	s.router = routes(s.ctx, s.logger, s.calls, s.records, s.callbacks, s.httpClient, s.trackRecords, s.requestValidation, s.stubSpec, s.adminAuth, s.adminPrefix)
+	s.server = s.newHTTPServer(nil, portTLS, !s.adminListen)
+
 	s.listener, err = net.Listen("tcp", fmt.Sprintf(":%d", s.Port))
 	if err != nil {
 		s.logger.Error("unable to create http listener", "port", s.Port, "error", err)
@@ -33,34 +73,212 @@ func NewServer(opts ...ServerOption) *Server {
 		s.Port = s.listener.Addr().(*net.TCPAddr).Port
 	}
 
+	newHandler := func(calls *Store[Call], records *Store[Record], callbacks *callbackScopes) http.Handler {
+		return routes(s.ctx, s.logger, calls, records, callbacks, s.httpClient, s.trackRecords, s.requestValidation, s.stubSpec, s.adminAuth, s.adminPrefix)
+	}
+	for _, service := range s.services {
+		vs := &virtualService{
+			Service:   service,
+			calls:     NewStore[Call](),
+			records:   NewStore[Record](),
+			callbacks: newCallbackScopes(s.ctx),
+		}
+		vs.handler = newHandler(vs.calls, vs.records, vs.callbacks)
+		if vs.Listen {
+			vs.server = s.newHTTPServer(vs.handler, portTLS, !s.adminListen)
+			vs.listener, err = net.Listen("tcp", fmt.Sprintf(":%d", vs.Port))
+			if err != nil {
+				s.logger.Error("unable to create service listener", "service", vs.Name, "port", vs.Port, "error", err)
+			} else {
+				vs.Port = vs.listener.Addr().(*net.TCPAddr).Port
+			}
+		}
+		s.virtualServices = append(s.virtualServices, vs)
+	}
+	s.server.Handler = &router{
+		ctx:        s.ctx,
+		fallback:   s.router,
+		services:   s.virtualServices,
+		newHandler: newHandler,
+		ca:         s.ca,
+		auth:       s.adminAuth,
+		prefix:     s.adminPrefix,
+		sessionTTL: s.sessionTTL,
+	}
+
+	if s.adminListen {
+		s.adminServer = s.newHTTPServer(s.server.Handler, portTLS, true)
+		s.adminListener, err = net.Listen("tcp", fmt.Sprintf(":%d", s.AdminPort))
+		if err != nil {
+			s.logger.Error("unable to create admin listener", "port", s.AdminPort, "error", err)
+		} else {
+			s.AdminPort = s.adminListener.Addr().(*net.TCPAddr).Port
+		}
+	}
+
+	if s.httpAndHTTPS && s.tlsConfig != nil {
+		s.tlsServer = s.newHTTPServer(s.server.Handler, s.tlsConfig, !s.adminListen)
+		s.tlsListener, err = net.Listen("tcp", fmt.Sprintf(":%d", s.TLSPort))
+		if err != nil {
+			s.logger.Error("unable to create https listener", "port", s.TLSPort, "error", err)
+		} else {
+			s.TLSPort = s.tlsListener.Addr().(*net.TCPAddr).Port
+		}
+	}
+
+	if s.grpcFiles != nil {
+		opts := []grpc.ServerOption{}
+		if s.tlsConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(s.tlsConfig.Clone())))
+		}
+		s.grpcServer = newGRPCServer(s.logger, s.httpClient, s.calls, s.records, s.callbacks, s.trackRecords, s.grpcFiles, opts...)
+		s.grpcListener, err = net.Listen("tcp", fmt.Sprintf(":%d", s.GRPCPort))
+		if err != nil {
+			s.logger.Error("unable to create grpc listener", "port", s.GRPCPort, "error", err)
+		} else {
+			s.GRPCPort = s.grpcListener.Addr().(*net.TCPAddr).Port
+		}
+	}
+
 	return &s
 }
 
 // Serve starts the Rest Assured client to begin listening on the application endpoints
 func (s *Server) Serve(ctx context.Context) error {
-	if s.listener == nil {
+	if s.listener == nil || (s.grpcServer != nil && s.grpcListener == nil) || (s.tlsEnabled() && s.tlsConfig == nil) {
 		return fmt.Errorf("invalid server")
 	}
+	if (s.tlsServer != nil && s.tlsListener == nil) || (s.adminServer != nil && s.adminListener == nil) {
+		return fmt.Errorf("invalid server")
+	}
+	for _, service := range s.virtualServices {
+		if service.Listen && service.listener == nil {
+			return fmt.Errorf("invalid service %s", service.Name)
+		}
+	}
 
-	go func() {
-		if s.tlsCertFile != "" && s.tlsKeyFile != "" {
-			http.ServeTLS(s.listener, s.router, s.tlsCertFile, s.tlsKeyFile)
-		} else {
-			http.Serve(s.listener, s.router)
+	go s.serve(ctx, s.server, s.listener)
+	if s.tlsServer != nil {
+		go s.serve(ctx, s.tlsServer, s.tlsListener)
+	}
+	if s.adminServer != nil {
+		go s.serve(ctx, s.adminServer, s.adminListener)
+	}
+	for _, service := range s.virtualServices {
+		if service.listener != nil {
+			go s.serve(ctx, service.server, service.listener)
 		}
-	}()
+	}
+	if s.grpcServer != nil {
+		go func() {
+			if err := s.grpcServer.Serve(s.grpcListener); err != nil {
+				s.logger.ErrorContext(ctx, "assured grpc server stopped serving", "error", err)
+			}
+		}()
+	}
 	return nil
 }
 
+// newHTTPServer creates an http server for the handler. Servers that do not serve the admin endpoints
+// serve their paths as stubbed endpoints.
+func (s *Server) newHTTPServer(handler http.Handler, tlsConfig *tls.Config, admin bool) *http.Server {
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		Protocols:         s.httpProtocols(),
+		TLSConfig:         tlsConfig,
+	}
+	if !admin {
+		server.BaseContext = func(net.Listener) context.Context {
+			return context.WithValue(context.Background(), stubsOnlyKey{}, true)
+		}
+	}
+	return server
+}
+
+// serve serves http, or https when the server has a tls config, on a listener until the server is closed
+func (s *Server) serve(ctx context.Context, server *http.Server, listener net.Listener) {
+	var err error
+	if server.TLSConfig != nil {
+		err = server.ServeTLS(listener, "", "")
+	} else {
+		err = server.Serve(listener)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.ErrorContext(ctx, "assured server stopped serving", "error", err)
+	}
+}
+
 // URL returns the url to use to test you stubbed endpoints
 func (s *Server) URL() string {
 	return s.url()
 }
 
-// Close is used to close the running service
+// AdminURL returns the url of the assured admin endpoints. When the admin endpoints are served on their
+// own listener, this is the url of the admin listener.
+func (s *Server) AdminURL() string {
+	return s.adminURL()
+}
+
+// TLSURL returns the url to use to test your stubbed endpoints over https, or an empty string when tls is
+// not configured. When serving http and https, this is the url of the https listener.
+func (s *Server) TLSURL() string {
+	return s.tlsURL()
+}
+
+// ServiceURL returns the url to use to test a virtual service's stubbed endpoints. Services routed by their
+// Host header share the server's url, and requests to them must set the Host header.
+func (s *Server) ServiceURL(name string) string {
+	for _, service := range s.virtualServices {
+		if service.Name != name {
+			continue
+		}
+		switch {
+		case service.listener != nil:
+			return buildURL(s.schema(), s.host, service.Port)
+		case service.prefix() != "":
+			return s.url() + service.prefix()
+		}
+		return s.url()
+	}
+	return ""
+}
+
+// GRPCAddr returns the address to use to test your stubbed gRPC methods
+func (s *Server) GRPCAddr() string {
+	return fmt.Sprintf("%s:%d", s.host, s.GRPCPort)
+}
+
+// Close is used to close the running service, cancelling any pending callbacks
 func (s *Server) Close() error {
-	if s.listener != nil {
-		return s.listener.Close()
+	s.cancel()
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
+	}
+	if s.listener == nil {
+		return nil
+	}
+	for _, service := range s.virtualServices {
+		if service.listener == nil {
+			continue
+		}
+		if err := service.server.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			return err
+		}
+	}
+	for _, server := range []*http.Server{s.tlsServer, s.adminServer} {
+		if server == nil {
+			continue
+		}
+		if err := server.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			return err
+		}
+	}
+	if err := s.server.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	if err := s.listener.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
 	}
 	return nil
 }
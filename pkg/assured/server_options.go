@@ -4,13 +4,25 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
+// DefaultAdminPrefix is the path prefix of the assured admin endpoints, such as /assured/given
+const DefaultAdminPrefix = "/assured"
+
+// DefaultSessionTTL is how long a session may go unused before it is ended
+const DefaultSessionTTL = time.Hour
+
 var DefaultServerOptions = ServerOptions{
 	httpClient:   http.DefaultClient,
 	host:         "localhost",
 	trackRecords: true,
 	logger:       slog.Default(),
+	adminPrefix:  DefaultAdminPrefix,
+	sessionTTL:   DefaultSessionTTL,
 }
 
 // ServerOption configures the server behavior.
@@ -33,11 +45,80 @@ type ServerOptions struct {
 	// tlsKeyFile is the location of the tls key for serving https.
 	tlsKeyFile string
 
+	// generateTLS serves https with a certificate issued by a generated certificate authority.
+	generateTLS bool
+
+	// mutualTLS verifies the client certificates of https requests.
+	mutualTLS *mutualTLS
+
+	// httpAndHTTPS serves plaintext http on Port alongside https on TLSPort, when tls is configured.
+	httpAndHTTPS bool
+
+	// TLSPort for the https listener to listen on, when serving http and https. Defaults to any available port.
+	TLSPort int
+
 	// trackRecords toggles storing the requests made against the assured server. Defaults to true.
 	trackRecords bool
 
 	// logger to use for logging. Defaults to the default logger.
 	logger *slog.Logger
+
+	// requestValidation validates requests made against stubbed calls with an OpenAPI document.
+	requestValidation *requestValidation
+
+	// stubSpec validates the responses of stubbed calls with an OpenAPI document.
+	stubSpec *OpenAPI
+
+	// grpcFiles are the protobuf descriptors of the gRPC services to stub. gRPC is served when set.
+	grpcFiles *protoregistry.Files
+
+	// GRPCPort for the gRPC server to listen on. Defaults to any available port.
+	GRPCPort int
+
+	// services are the named virtual services hosted alongside the default service.
+	services []Service
+
+	// adminAuth is the credentials required by the assured admin endpoints. Defaults to none.
+	adminAuth *adminAuth
+
+	// adminPrefix is the path prefix of the assured admin endpoints. Defaults to /assured.
+	adminPrefix string
+
+	// adminListen serves the admin endpoints on AdminPort, and only stubbed endpoints on Port.
+	adminListen bool
+
+	// AdminPort for the admin listener to listen on, when serving the admin endpoints separately. Defaults to any available port.
+	AdminPort int
+
+	// protocols are the http protocols served. Defaults to HTTP/1.1, and HTTP/2 over TLS.
+	protocols []Protocol
+
+	// sessionTTL is how long a session may go unused before it is ended. Defaults to an hour.
+	sessionTTL time.Duration
+}
+
+// Protocol is an http protocol the server may serve
+type Protocol string
+
+const (
+	// ProtocolHTTP1 serves HTTP/1.1
+	ProtocolHTTP1 Protocol = "http1"
+	// ProtocolHTTP2 serves HTTP/2 over TLS
+	ProtocolHTTP2 Protocol = "h2"
+	// ProtocolH2C serves HTTP/2 over cleartext to clients with prior knowledge
+	ProtocolH2C Protocol = "h2c"
+)
+
+// mutualTLS is how client certificates are verified, against a client ca file or the generated certificate authority.
+type mutualTLS struct {
+	caFile   string
+	required bool
+}
+
+// requestValidation pairs an OpenAPI document with how non-conforming requests are handled.
+type requestValidation struct {
+	spec *OpenAPI
+	mode ValidationMode
 }
 
 func (o *ServerOptions) applyOptions(opts ...ServerOption) {
@@ -79,6 +160,92 @@ func WithTLS(cert, key string) ServerOption {
 	}
 }
 
+// WithGeneratedTLS serves https with a certificate for the host issued by an ephemeral certificate authority,
+// generated when the server is created.
+func WithGeneratedTLS() ServerOption {
+	return func(o *ServerOptions) {
+		o.generateTLS = true
+	}
+}
+
+// WithMutualTLS verifies client certificates against the certificate authorities in the client ca file, or the
+// generated certificate authority when empty. Connections without a client certificate are rejected when required.
+func WithMutualTLS(clientCAFile string, required bool) ServerOption {
+	return func(o *ServerOptions) {
+		o.mutualTLS = &mutualTLS{caFile: clientCAFile, required: required}
+	}
+}
+
+// WithHTTPAndHTTPS serves plaintext http on the port alongside https on the tls port, sharing the same stubbed
+// calls and records, when tls is configured.
+func WithHTTPAndHTTPS() ServerOption {
+	return func(o *ServerOptions) {
+		o.httpAndHTTPS = true
+	}
+}
+
+// WithTLSPort sets the tls port option.
+func WithTLSPort(p int) ServerOption {
+	return func(o *ServerOptions) {
+		if p != 0 {
+			o.TLSPort = p
+		}
+	}
+}
+
+// WithAdminToken requires the assured admin endpoints to be called with the bearer token.
+func WithAdminToken(token string) ServerOption {
+	return func(o *ServerOptions) {
+		if token != "" {
+			auth := adminAuth{}
+			if o.adminAuth != nil {
+				auth = *o.adminAuth
+			}
+			auth.token = token
+			o.adminAuth = &auth
+		}
+	}
+}
+
+// WithAdminBasicAuth requires the assured admin endpoints to be called with the basic auth credentials.
+func WithAdminBasicAuth(username, password string) ServerOption {
+	return func(o *ServerOptions) {
+		if username != "" {
+			auth := adminAuth{}
+			if o.adminAuth != nil {
+				auth = *o.adminAuth
+			}
+			auth.username, auth.password = username, password
+			o.adminAuth = &auth
+		}
+	}
+}
+
+// WithAdminPrefix sets the path prefix of the assured admin endpoints, so the default /assured paths can be stubbed.
+func WithAdminPrefix(prefix string) ServerOption {
+	return func(o *ServerOptions) {
+		if p := strings.Trim(prefix, "/"); p != "" {
+			o.adminPrefix = "/" + p
+		}
+	}
+}
+
+// WithAdminListener serves the assured admin endpoints on the admin port, and only stubbed endpoints on the port.
+func WithAdminListener() ServerOption {
+	return func(o *ServerOptions) {
+		o.adminListen = true
+	}
+}
+
+// WithAdminPort sets the admin port option.
+func WithAdminPort(p int) ServerOption {
+	return func(o *ServerOptions) {
+		if p != 0 {
+			o.AdminPort = p
+		}
+	}
+}
+
 // WithCallTracking sets the trackMadeCalls option.
 func WithCallTracking(t bool) ServerOption {
 	return func(o *ServerOptions) {
@@ -95,13 +262,120 @@ func WithLogger(l *slog.Logger) ServerOption {
 	}
 }
 
+// WithRequestValidation validates every request made against stubbed calls with an OpenAPI document.
+// Non-conforming requests are rejected or recorded with their violations, depending on the mode.
+func WithRequestValidation(spec *OpenAPI, mode ValidationMode) ServerOption {
+	return func(o *ServerOptions) {
+		if spec != nil {
+			o.requestValidation = &requestValidation{spec: spec, mode: mode}
+		}
+	}
+}
+
+// WithStubValidation rejects stubbed calls whose status code, headers or response body
+// do not conform to the matching operation of an OpenAPI document.
+func WithStubValidation(spec *OpenAPI) ServerOption {
+	return func(o *ServerOptions) {
+		o.stubSpec = spec
+	}
+}
+
+// WithGRPC serves gRPC alongside REST, responding to the methods of the services in the protobuf
+// descriptors with stubbed calls. gRPC calls always use the default service's stubs and records, virtual services
+// and sessions do not apply to them.
+func WithGRPC(files *protoregistry.Files) ServerOption {
+	return func(o *ServerOptions) {
+		o.grpcFiles = files
+	}
+}
+
+// WithGRPCPort sets the gRPC port option.
+func WithGRPCPort(p int) ServerOption {
+	return func(o *ServerOptions) {
+		if p != 0 {
+			o.GRPCPort = p
+		}
+	}
+}
+
+// WithService hosts a named virtual service, with its own stubbed calls and records, alongside the default service.
+func WithService(service Service) ServerOption {
+	return func(o *ServerOptions) {
+		if service.Name != "" {
+			o.services = append(o.services, service)
+		}
+	}
+}
+
+// WithProtocols sets the http protocols served, such as only HTTP/1.1, or HTTP/2 over cleartext.
+func WithProtocols(protocols ...Protocol) ServerOption {
+	return func(o *ServerOptions) {
+		o.protocols = protocols
+	}
+}
+
+// WithSessionTTL sets how long a session may go unused before it is ended, discarding its stubbed calls and
+// records. A TTL of 0 keeps sessions until they are ended.
+func WithSessionTTL(ttl time.Duration) ServerOption {
+	return func(o *ServerOptions) {
+		o.sessionTTL = ttl
+	}
+}
+
+// httpProtocols returns the http protocols served, or nil for the http server's defaults.
+func (o *ServerOptions) httpProtocols() *http.Protocols {
+	if len(o.protocols) == 0 {
+		return nil
+	}
+	protocols := &http.Protocols{}
+	for _, protocol := range o.protocols {
+		switch protocol {
+		case ProtocolHTTP1:
+			protocols.SetHTTP1(true)
+		case ProtocolHTTP2:
+			protocols.SetHTTP2(true)
+		case ProtocolH2C:
+			protocols.SetUnencryptedHTTP2(true)
+		}
+	}
+	return protocols
+}
+
 // url returns the url to used by the client internally.
 func (o *ServerOptions) url() string {
-	schema := "http"
-	if o.tlsCertFile != "" && o.tlsKeyFile != "" {
-		schema = "https"
+	return buildURL(o.schema(), o.host, o.Port)
+}
+
+// adminURL returns the url of the admin endpoints' listener.
+func (o *ServerOptions) adminURL() string {
+	if o.adminListen {
+		return buildURL(o.schema(), o.host, o.AdminPort)
+	}
+	return o.url()
+}
+
+// tlsURL returns the url of the https listener, or an empty string when tls is not configured.
+func (o *ServerOptions) tlsURL() string {
+	switch {
+	case !o.tlsEnabled():
+		return ""
+	case o.httpAndHTTPS:
+		return buildURL("https", o.host, o.TLSPort)
 	}
-	return buildURL(schema, o.host, o.Port)
+	return o.url()
+}
+
+// schema returns the url schema the server's port is served with.
+func (o *ServerOptions) schema() string {
+	if o.tlsEnabled() && !o.httpAndHTTPS {
+		return "https"
+	}
+	return "http"
+}
+
+// tlsEnabled returns whether https is served, with tls cert and key files or a generated certificate.
+func (o *ServerOptions) tlsEnabled() bool {
+	return o.generateTLS || (o.tlsCertFile != "" && o.tlsKeyFile != "")
 }
 
 func buildURL(schema, host string, port int) string {
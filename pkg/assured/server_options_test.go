@@ -6,10 +6,14 @@ import (
 	"os"
 	"reflect"
 	"testing"
+
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
 func TestServerOptions_applyOptions(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{}))
+	spec := &OpenAPI{OpenAPI: "3.1.0"}
+	files := &protoregistry.Files{}
 	tests := []struct {
 		name   string
 		option ServerOption
@@ -58,6 +62,116 @@ func TestServerOptions_applyOptions(t *testing.T) {
 				logger: logger,
 			},
 		},
+		{
+			name:   "with request validation",
+			option: WithRequestValidation(spec, ValidationReject),
+			want: ServerOptions{
+				requestValidation: &requestValidation{spec: spec, mode: ValidationReject},
+			},
+		},
+		{
+			name:   "with stub validation",
+			option: WithStubValidation(spec),
+			want: ServerOptions{
+				stubSpec: spec,
+			},
+		},
+		{
+			name:   "with grpc",
+			option: WithGRPC(files),
+			want: ServerOptions{
+				grpcFiles: files,
+			},
+		},
+		{
+			name:   "with service",
+			option: WithService(Service{Name: "payments", PathPrefix: "payments"}),
+			want: ServerOptions{
+				services: []Service{{Name: "payments", PathPrefix: "payments"}},
+			},
+		},
+		{
+			name:   "with unnamed service",
+			option: WithService(Service{Host: "payments.local"}),
+			want:   ServerOptions{},
+		},
+		{
+			name:   "with generated tls",
+			option: WithGeneratedTLS(),
+			want: ServerOptions{
+				generateTLS: true,
+			},
+		},
+		{
+			name:   "with mutual tls",
+			option: WithMutualTLS("ca.pem", true),
+			want: ServerOptions{
+				mutualTLS: &mutualTLS{caFile: "ca.pem", required: true},
+			},
+		},
+		{
+			name:   "with http and https",
+			option: WithHTTPAndHTTPS(),
+			want: ServerOptions{
+				httpAndHTTPS: true,
+			},
+		},
+		{
+			name:   "with tls port",
+			option: WithTLSPort(9443),
+			want: ServerOptions{
+				TLSPort: 9443,
+			},
+		},
+		{
+			name:   "with admin token",
+			option: WithAdminToken("secret"),
+			want: ServerOptions{
+				adminAuth: &adminAuth{token: "secret"},
+			},
+		},
+		{
+			name:   "with admin basic auth",
+			option: WithAdminBasicAuth("admin", "secret"),
+			want: ServerOptions{
+				adminAuth: &adminAuth{username: "admin", password: "secret"},
+			},
+		},
+		{
+			name:   "with admin prefix",
+			option: WithAdminPrefix("/_admin/"),
+			want: ServerOptions{
+				adminPrefix: "/_admin",
+			},
+		},
+		{
+			name:   "with admin listener",
+			option: WithAdminListener(),
+			want: ServerOptions{
+				adminListen: true,
+			},
+		},
+		{
+			name:   "with admin port",
+			option: WithAdminPort(9091),
+			want: ServerOptions{
+				AdminPort: 9091,
+			},
+		},
+		{
+			name:   "with protocols",
+			option: WithProtocols(ProtocolHTTP1, ProtocolH2C),
+			want: ServerOptions{
+				protocols: []Protocol{ProtocolHTTP1, ProtocolH2C},
+			},
+		},
+		{
+			name:   "with grpc port",
+			option: WithGRPCPort(9090),
+			want: ServerOptions{
+				GRPCPort: 9090,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
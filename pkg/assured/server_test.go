@@ -1,7 +1,9 @@
 package assured
 
 import (
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -11,3 +13,46 @@ func TestClientInvalidPort(t *testing.T) {
 
 	require.Error(t, client.Serve(t.Context()))
 }
+
+func TestServerHTTPClientKeepsTransport(t *testing.T) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.MaxIdleConnsPerHost = 7
+	server := NewServer(WithGeneratedTLS(), WithHTTPClient(http.Client{Transport: base, Timeout: time.Second}))
+
+	client := server.HTTPClient()
+	require.Equal(t, time.Second, client.Timeout)
+	transport, ok := client.Transport.(*http.Transport)
+	require.True(t, ok)
+	require.NotSame(t, base, transport)
+	require.Equal(t, 7, transport.MaxIdleConnsPerHost)
+	require.NotNil(t, transport.TLSClientConfig.RootCAs)
+	require.Len(t, transport.TLSClientConfig.Certificates, 1)
+	again := server.HTTPClient().Transport.(*http.Transport)
+	require.Same(t, transport.TLSClientConfig.Certificates[0].Leaf, again.TLSClientConfig.Certificates[0].Leaf)
+	if base.TLSClientConfig != nil {
+		require.Nil(t, base.TLSClientConfig.RootCAs)
+	}
+}
+
+func TestServerGeneratedCertificateValidity(t *testing.T) {
+	server := NewServer(WithGeneratedTLS())
+
+	require.True(t, server.ca.cert.NotAfter.After(time.Now().AddDate(9, 0, 0)))
+	cert, err := server.ClientCertificate("client")
+	require.NoError(t, err)
+	require.True(t, cert.Leaf.NotAfter.After(time.Now().AddDate(9, 0, 0)))
+}
+
+func TestNewAssuredClientH2CKeepsTransport(t *testing.T) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.MaxIdleConnsPerHost = 7
+	server := NewServer(WithProtocols(ProtocolH2C), WithHTTPClient(http.Client{Transport: base}))
+
+	client := newAssuredClient(server)
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	require.True(t, ok)
+	require.NotSame(t, base, transport)
+	require.Equal(t, 7, transport.MaxIdleConnsPerHost)
+	require.True(t, transport.Protocols.UnencryptedHTTP2())
+	require.Nil(t, base.Protocols)
+}
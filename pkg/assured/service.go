@@ -0,0 +1,243 @@
+package assured
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ServiceHeader selects the virtual service, by name, that a request is made against
+	ServiceHeader = "Assured-Service"
+	// SessionHeader selects the session, by id, that a request is made against
+	SessionHeader = "Assured-Session"
+
+	// sessionsPath, after the admin prefix, starts sessions and prefixes the paths of requests made against a session
+	sessionsPath = "/sessions"
+)
+
+// Service is a structure containing a named virtual service hosted by the assured server, with its own
+// stubbed calls and records. Requests are routed to the service by the ServiceHeader, their Host header,
+// their path prefix, or the service's own listener.
+type Service struct {
+	Name string `json:"name"`
+
+	// Host routes requests whose Host header, without its port, matches
+	Host string `json:"host,omitempty"`
+
+	// PathPrefix routes requests whose path starts with the prefix, which is removed before matching stubs
+	PathPrefix string `json:"path_prefix,omitempty"`
+
+	// Listen serves the service on its own listener at Port, or any available port when Port is 0
+	Listen bool `json:"listen,omitempty"`
+	Port   int  `json:"port,omitempty"`
+}
+
+// virtualService is a Service with its stores and the handler serving its routes
+type virtualService struct {
+	Service
+	calls     *Store[Call]
+	records   *Store[Record]
+	callbacks *callbackScopes
+	handler   http.Handler
+	listener  net.Listener
+	server    *http.Server
+
+	// used is when a session was last used, to end idle sessions
+	used time.Time
+}
+
+// prefix returns the service's path prefix with a leading slash, or an empty string
+func (s *virtualService) prefix() string {
+	if p := strings.Trim(s.PathPrefix, "/"); p != "" {
+		return "/" + p
+	}
+	return ""
+}
+
+// Session is a structure containing an isolated session, with its own stubbed calls and records
+type Session struct {
+	ID string `json:"id"`
+}
+
+// router routes requests to the session or virtual service they select, or else to the default handler
+type router struct {
+	ctx        context.Context
+	fallback   http.Handler
+	services   []*virtualService
+	newHandler func(calls *Store[Call], records *Store[Record], callbacks *callbackScopes) http.Handler
+	ca         *certificateAuthority
+	auth       *adminAuth
+	prefix     string
+	sessionTTL time.Duration
+
+	sessions map[string]*virtualService
+	sync.Mutex
+}
+
+// ServeHTTP routes a request to its session or virtual service. Requests made to a listener that serves
+// only stubbed endpoints select sessions by their header alone.
+func (rt *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sessions := rt.prefix + sessionsPath
+	sessionsV2 := rt.prefix + v2Path + sessionsPath
+	switch {
+	case stubsOnly(r):
+	case r.URL.Path == rt.prefix+caPath && r.Method == http.MethodGet:
+		handleCA(rt.ca).ServeHTTP(w, r)
+		return
+	case r.URL.Path == sessionsV2 && r.Method == http.MethodPost:
+		requireAdminAuth(rt.auth, problem, http.HandlerFunc(rt.handleCreateSessionV2)).ServeHTTP(w, r)
+		return
+	case strings.HasPrefix(r.URL.Path, sessionsV2+"/") && r.Method == http.MethodDelete:
+		requireAdminAuth(rt.auth, problem, http.HandlerFunc(rt.handleDeleteSessionV2)).ServeHTTP(w, r)
+		return
+	case r.URL.Path == sessions && r.Method == http.MethodPost:
+		requireAdminAuth(rt.auth, apiError, http.HandlerFunc(rt.handleNewSession)).ServeHTTP(w, r)
+		return
+	case strings.HasPrefix(r.URL.Path, sessions+"/"):
+		id, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, sessions+"/"), "/")
+		if rest == "" && r.Method == http.MethodDelete {
+			requireAdminAuth(rt.auth, apiError, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rt.handleEndSession(w, r, id)
+			})).ServeHTTP(w, r)
+			return
+		}
+		if session := rt.session(id); session != nil {
+			http.StripPrefix(sessions+"/"+id, session.handler).ServeHTTP(w, r)
+			return
+		}
+		_ = encode(w, http.StatusNotFound, APIError{fmt.Sprintf("unknown session %s", id)})
+		return
+	}
+	if id := r.Header.Get(SessionHeader); id != "" {
+		if session := rt.session(id); session != nil {
+			session.handler.ServeHTTP(w, r)
+			return
+		}
+		_ = encode(w, http.StatusNotFound, APIError{fmt.Sprintf("unknown session %s", id)})
+		return
+	}
+
+	if name := r.Header.Get(ServiceHeader); name != "" {
+		for _, service := range rt.services {
+			if service.Name == name {
+				service.handler.ServeHTTP(w, r)
+				return
+			}
+		}
+		_ = encode(w, http.StatusNotFound, APIError{fmt.Sprintf("unknown service %s", name)})
+		return
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		host = h
+	}
+	for _, service := range rt.services {
+		if service.Host != "" && strings.EqualFold(service.Host, host) {
+			service.handler.ServeHTTP(w, r)
+			return
+		}
+	}
+	for _, service := range rt.services {
+		prefix := service.prefix()
+		if prefix != "" && (r.URL.Path == prefix || strings.HasPrefix(r.URL.Path, prefix+"/")) {
+			http.StripPrefix(prefix, service.handler).ServeHTTP(w, r)
+			return
+		}
+	}
+	rt.fallback.ServeHTTP(w, r)
+}
+
+// session returns the session with the id, marking it used, or nil
+func (rt *router) session(id string) *virtualService {
+	rt.Lock()
+	defer rt.Unlock()
+	rt.endIdleSessions()
+	session := rt.sessions[id]
+	if session != nil {
+		session.used = time.Now()
+	}
+	return session
+}
+
+// handleNewSession starts a session with its own stubbed calls and records
+func (rt *router) handleNewSession(w http.ResponseWriter, _ *http.Request) {
+	_ = encode(w, http.StatusOK, Session{ID: rt.newSession()})
+}
+
+// handleEndSession ends a session, discarding its stubbed calls and records
+func (rt *router) handleEndSession(w http.ResponseWriter, _ *http.Request, id string) {
+	if !rt.endSession(id) {
+		_ = encode(w, http.StatusNotFound, APIError{fmt.Sprintf("unknown session %s", id)})
+	}
+}
+
+// handleCreateSessionV2 starts a session, responding with the session and its location
+func (rt *router) handleCreateSessionV2(w http.ResponseWriter, r *http.Request) {
+	id := rt.newSession()
+	w.Header().Set("Location", r.URL.Path+"/"+id)
+	_ = encode(w, http.StatusCreated, Session{ID: id})
+}
+
+// handleDeleteSessionV2 ends the session at the request path
+func (rt *router) handleDeleteSessionV2(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, rt.prefix+v2Path+sessionsPath+"/")
+	if !rt.endSession(id) {
+		problem(w, http.StatusNotFound, fmt.Sprintf("unknown session %s", id))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newSession starts a session with its own stubbed calls and records, returning its id
+func (rt *router) newSession() string {
+	session := &virtualService{
+		Service:   Service{Name: rand.Text()},
+		calls:     NewStore[Call](),
+		records:   NewStore[Record](),
+		callbacks: newCallbackScopes(rt.ctx),
+		used:      time.Now(),
+	}
+	session.handler = rt.newHandler(session.calls, session.records, session.callbacks)
+
+	rt.Lock()
+	rt.endIdleSessions()
+	if rt.sessions == nil {
+		rt.sessions = map[string]*virtualService{}
+	}
+	rt.sessions[session.Name] = session
+	rt.Unlock()
+	return session.Name
+}
+
+// endSession ends a session, discarding its stubbed calls and records and stopping its callbacks, and returns
+// whether it existed
+func (rt *router) endSession(id string) bool {
+	rt.Lock()
+	defer rt.Unlock()
+	session, ok := rt.sessions[id]
+	if ok {
+		session.callbacks.cancelAll()
+	}
+	delete(rt.sessions, id)
+	return ok
+}
+
+// endIdleSessions ends the sessions unused for longer than the session TTL. The router must be locked.
+func (rt *router) endIdleSessions() {
+	if rt.sessionTTL <= 0 {
+		return
+	}
+	for id, session := range rt.sessions {
+		if time.Since(session.used) > rt.sessionTTL {
+			session.callbacks.cancelAll()
+			delete(rt.sessions, id)
+		}
+	}
+}
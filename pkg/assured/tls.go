@@ -0,0 +1,228 @@
+package assured
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// caPath, after the admin prefix, serves the PEM encoded certificate authority of a server with generated TLS
+const caPath = "/ca"
+
+// certificateValidity is how long generated certificates are valid for, long enough to outlive any test run or
+// long-running mock
+const certificateValidity = 10 * 365 * 24 * time.Hour
+
+// certificateAuthority is an ephemeral certificate authority that issues the server's and clients' certificates
+type certificateAuthority struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+	pem  []byte
+}
+
+// newCertificateAuthority generates an ephemeral certificate authority
+func newCertificateAuthority() (*certificateAuthority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate ca key: %w", err)
+	}
+	template, err := certificateTemplate("Assured CA")
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("create ca certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse ca certificate: %w", err)
+	}
+	return &certificateAuthority{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}, nil
+}
+
+// issue creates a certificate signed by the certificate authority. Server certificates are valid for the hosts,
+// which may be DNS names or IP addresses, and client certificates are identified by the common name.
+func (ca *certificateAuthority) issue(commonName string, usage x509.ExtKeyUsage, hosts ...string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("generate certificate key: %w", err)
+	}
+	template, err := certificateTemplate(commonName)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("create certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("parse certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// pool returns a certificate pool trusting the certificate authority
+func (ca *certificateAuthority) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// certificateTemplate returns a certificate template valid from an hour ago for the certificate validity
+func certificateTemplate(commonName string) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generate certificate serial: %w", err)
+	}
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"go-rest-assured"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(certificateValidity),
+	}, nil
+}
+
+// newTLSConfig builds the server's tls configuration from the tls cert and key files, or a certificate issued by
+// a generated certificate authority, and verifies client certificates when mutual TLS is configured
+func (s *Server) newTLSConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	switch {
+	case s.generateTLS:
+		ca, err := newCertificateAuthority()
+		if err != nil {
+			return nil, err
+		}
+		cert, err := ca.issue(s.host, x509.ExtKeyUsageServerAuth, s.host, "localhost", "127.0.0.1", "::1")
+		if err != nil {
+			return nil, err
+		}
+		clientCert, err := ca.issue("assured-client", x509.ExtKeyUsageClientAuth)
+		if err != nil {
+			return nil, err
+		}
+		s.ca = ca
+		s.clientCert = clientCert
+		config.Certificates = []tls.Certificate{cert}
+	case s.tlsCertFile != "" && s.tlsKeyFile != "":
+		cert, err := tls.LoadX509KeyPair(s.tlsCertFile, s.tlsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load tls certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	default:
+		return nil, nil
+	}
+
+	if s.mutualTLS == nil {
+		return config, nil
+	}
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	if s.mutualTLS.required {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	switch {
+	case s.mutualTLS.caFile != "":
+		b, err := os.ReadFile(s.mutualTLS.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client ca file: %w", err)
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in client ca file %s", s.mutualTLS.caFile)
+		}
+	case s.ca != nil:
+		config.ClientCAs = s.ca.pool()
+	default:
+		return nil, errors.New("mutual tls requires a client ca file or generated tls")
+	}
+	return config, nil
+}
+
+// CACertificate returns the PEM encoded certificate authority that issued the server's generated certificate,
+// or nil when the server's TLS is not generated
+func (s *Server) CACertificate() []byte {
+	if s.ca == nil {
+		return nil
+	}
+	return s.ca.pem
+}
+
+// ClientCertificate issues a client certificate with the common name from the generated certificate authority,
+// which is trusted by servers with generated TLS and mutual TLS
+func (s *Server) ClientCertificate(commonName string) (tls.Certificate, error) {
+	if s.ca == nil {
+		return tls.Certificate{}, errors.New("server tls is not generated")
+	}
+	return s.ca.issue(commonName, x509.ExtKeyUsageClientAuth)
+}
+
+// HTTPClient returns an http client to test the server with. With generated TLS, the client trusts the
+// generated certificate authority and presents the client certificate issued by it when the server started, on a
+// clone of the configured http client's transport.
+func (s *Server) HTTPClient() *http.Client {
+	client := *s.httpClient
+	if s.ca == nil {
+		return &client
+	}
+	var transport *http.Transport
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		// A custom round tripper's TLS cannot be configured
+		return &client
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	transport.TLSClientConfig.RootCAs = s.ca.pool()
+	transport.TLSClientConfig.Certificates = []tls.Certificate{s.clientCert}
+	client.Transport = transport
+	return &client
+}
+
+// handleCA responds with the PEM encoded certificate authority of the server's generated TLS
+func handleCA(ca *certificateAuthority) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if ca == nil {
+			_ = encode(w, http.StatusNotFound, APIError{"server tls is not generated"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		_, _ = w.Write(ca.pem)
+	}
+}
@@ -0,0 +1,205 @@
+package assured
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+const (
+	// WebSocketReceived is the Record method of the messages received from a WebSocket client
+	WebSocketReceived = "WS_RECEIVED"
+	// WebSocketSent is the Record method of the messages sent to a WebSocket client
+	WebSocketSent = "WS_SENT"
+)
+
+// WebSocket is a structure containing a scripted WebSocket conversation
+type WebSocket struct {
+	// Subprotocols are the subprotocols the server may negotiate with the client
+	Subprotocols []string `json:"subprotocols,omitempty"`
+
+	// OnConnect messages are sent as soon as the connection is upgraded
+	OnConnect []WebSocketMessage `json:"on_connect,omitempty"`
+
+	// Replies are checked in order against each incoming message, and the first matching reply is sent
+	Replies []WebSocketReply `json:"replies,omitempty"`
+
+	// Close closes the connection once the OnConnect messages are sent. Otherwise the connection
+	// stays open until the client or a reply closes it.
+	Close *WebSocketClose `json:"close,omitempty"`
+}
+
+// WebSocketMessage is a structure containing a message sent to a WebSocket client
+type WebSocketMessage struct {
+	// Text, JSON and Binary set the message sent. At most one may be set.
+	Text   string          `json:"text,omitempty"`
+	JSON   json.RawMessage `json:"json,omitempty"`
+	Binary []byte          `json:"binary,omitempty"`
+
+	// DelayMS is how many milliseconds to wait before sending the message
+	DelayMS int `json:"delay_ms,omitempty"`
+}
+
+// WebSocketReply is a structure containing the messages sent when an incoming message matches
+type WebSocketReply struct {
+	// Match is a regular expression matched against incoming messages. An empty match replies to every message.
+	Match    string             `json:"match,omitempty"`
+	Messages []WebSocketMessage `json:"messages,omitempty"`
+
+	// Close closes the connection once the reply's messages are sent
+	Close *WebSocketClose `json:"close,omitempty"`
+}
+
+// WebSocketClose is a structure containing how a WebSocket connection is closed
+type WebSocketClose struct {
+	Code    int    `json:"code"`
+	Reason  string `json:"reason,omitempty"`
+	DelayMS int    `json:"delay_ms,omitempty"`
+}
+
+// message returns the type and data of the message
+func (m WebSocketMessage) message() (websocket.MessageType, []byte) {
+	switch {
+	case len(m.JSON) > 0:
+		return websocket.MessageText, m.JSON
+	case len(m.Binary) > 0:
+		return websocket.MessageBinary, m.Binary
+	}
+	return websocket.MessageText, []byte(m.Text)
+}
+
+// validateWebSocket checks that a scripted WebSocket conversation can be played
+func validateWebSocket(ws WebSocket) error {
+	messages := append([]WebSocketMessage{}, ws.OnConnect...)
+	closes := []*WebSocketClose{ws.Close}
+	for _, reply := range ws.Replies {
+		if _, err := regexp.Compile(reply.Match); err != nil {
+			return fmt.Errorf("invalid websocket reply match: %w", err)
+		}
+		messages = append(messages, reply.Messages...)
+		closes = append(closes, reply.Close)
+	}
+	for _, m := range messages {
+		set := 0
+		for _, ok := range []bool{m.Text != "", len(m.JSON) > 0, len(m.Binary) > 0} {
+			if ok {
+				set++
+			}
+		}
+		if set > 1 {
+			return errors.New("only one of websocket message text, json or binary may be set")
+		}
+		if m.DelayMS < 0 {
+			return errors.New("websocket message delay_ms cannot be negative")
+		}
+	}
+	for _, c := range closes {
+		if c != nil && (c.Code < 1000 || c.Code > 4999) {
+			return fmt.Errorf("invalid websocket close code %d", c.Code)
+		}
+	}
+	return nil
+}
+
+// serveWebSocket upgrades the connection and plays the assured Call's scripted conversation
+// until it is closed by the script, the client, or the server
+func serveWebSocket(
+	ctx context.Context,
+	logger *slog.Logger,
+	w http.ResponseWriter,
+	r *http.Request,
+	records *Store[Record],
+	trackRecords bool,
+	call Call,
+) {
+	ws := *call.WebSocket
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		Subprotocols:       ws.Subprotocols,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		logger.InfoContext(ctx, "failed to upgrade websocket connection", "key", call.Key(), "error", err)
+		return
+	}
+	defer func() { _ = conn.CloseNow() }()
+
+	record := func(method string, data []byte) {
+		if trackRecords {
+			records.Add(Record{Path: call.Path, Method: method, Body: data})
+		}
+	}
+	send := func(m WebSocketMessage) error {
+		if err := sleep(ctx, m.DelayMS); err != nil {
+			return err
+		}
+		typ, data := m.message()
+		if err := conn.Write(ctx, typ, data); err != nil {
+			return err
+		}
+		record(WebSocketSent, data)
+		return nil
+	}
+	closeWith := func(c WebSocketClose) {
+		if err := sleep(ctx, c.DelayMS); err != nil {
+			return
+		}
+		_ = conn.Close(websocket.StatusCode(c.Code), c.Reason)
+		logger.InfoContext(ctx, "assured websocket closed", "key", call.Key(), "code", c.Code)
+	}
+
+	for _, m := range ws.OnConnect {
+		if err := send(m); err != nil {
+			logger.InfoContext(ctx, "failed to send websocket message", "key", call.Key(), "error", err)
+			return
+		}
+	}
+	if ws.Close != nil {
+		closeWith(*ws.Close)
+		return
+	}
+
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			if status := websocket.CloseStatus(err); status == -1 && ctx.Err() == nil {
+				logger.InfoContext(ctx, "failed to read websocket message", "key", call.Key(), "error", err)
+			}
+			return
+		}
+		record(WebSocketReceived, data)
+
+		for _, reply := range ws.Replies {
+			if matched, _ := regexp.Match(reply.Match, data); !matched {
+				continue
+			}
+			for _, m := range reply.Messages {
+				if err := send(m); err != nil {
+					logger.InfoContext(ctx, "failed to send websocket message", "key", call.Key(), "error", err)
+					return
+				}
+			}
+			if reply.Close != nil {
+				closeWith(*reply.Close)
+				return
+			}
+			break
+		}
+	}
+}
+
+// sleep waits for a number of milliseconds, or until the context is done
+func sleep(ctx context.Context, ms int) error {
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-time.After(time.Duration(ms) * time.Millisecond):
+		return nil
+	}
+}